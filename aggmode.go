@@ -0,0 +1,38 @@
+package hstat
+
+// AggMode 表示同一个桶内多次写入时的聚合方式
+type AggMode int
+
+const (
+	// AggSum 累加写入的值，适用于计数器，是默认方式
+	AggSum AggMode = iota
+	// AggLast 用最新写入的值替换桶的值，适用于仪表盘类数据
+	AggLast
+	// AggMax 保留桶内写入过的最大值，适用于峰值跟踪
+	AggMax
+	// AggAvg 取多个桶的平均值，仅用于 Rollup 等跨桶的聚合；Add 中按 AggSum 处理
+	AggAvg
+)
+
+// Add 按窗口配置的聚合方式(参见 WithAggMode)将值写入当前桶
+func (w *TimeWindow) Add(value float64) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	now := w.now()
+	w.rotate(now)
+	w.lastUpdate = laterTime(w.lastUpdate, now)
+
+	switch w.aggMode {
+	case AggLast:
+		w.setBucket(w.cursor, value)
+	case AggMax:
+		if !w.populated(w.cursor) || value > w.buckets[w.cursor] {
+			w.setBucket(w.cursor, value)
+		}
+	default:
+		w.setBucket(w.cursor, w.buckets[w.cursor]+value)
+	}
+	w.countSamples(w.cursor, 1)
+	w.markUpdated()
+}
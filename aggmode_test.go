@@ -0,0 +1,40 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_AddModes(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+		want float64
+	}{
+		{"default sum", nil, 7},
+		{"sum", []Option{WithAggMode(AggSum)}, 7},
+		{"last", []Option{WithAggMode(AggLast)}, 2},
+		{"max", []Option{WithAggMode(AggMax)}, 4},
+	}
+
+	for _, c := range cases {
+		w := NewTimeWindow(5, time.Second, c.opts...)
+		w.Add(1)
+		w.Add(4)
+		w.Add(2)
+
+		if val, _ := w.GetLatestValue(); val != c.want {
+			t.Errorf("%s: expected %f, got %f", c.name, c.want, val)
+		}
+	}
+}
+
+func TestTimeWindow_AddMaxNegative(t *testing.T) {
+	w := NewTimeWindow(5, time.Second, WithAggMode(AggMax))
+	w.Add(-5)
+	w.Add(-3)
+
+	if val, _ := w.GetLatestValue(); val != -3 {
+		t.Errorf("Expected -3, got %f", val)
+	}
+}
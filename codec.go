@@ -0,0 +1,403 @@
+package hstat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WindowHeader 描述时间窗口的元数据，与按原始顺序排列的桶一起可以完整还原窗口
+type WindowHeader struct {
+	Size       int           // 窗口大小(桶的数量)
+	Duration   time.Duration // 每个桶的时间跨度
+	Cursor     int           // 当前桶的位置
+	LastTime   time.Time     // 上次更新时间
+	LastUpdate time.Time     // 最近一次数据更新时间
+}
+
+// Raw 在同一次加锁中返回窗口的元数据和桶的副本
+// 桶按内部存储顺序排列，需结合 Cursor 解释
+func (w *TimeWindow) Raw() (WindowHeader, []float64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.header(), append([]float64(nil), w.buckets...)
+}
+
+// RawBuckets 返回按内部存储顺序排列的桶的副本
+// 如需与 Header 保持一致，请使用 Raw
+func (w *TimeWindow) RawBuckets() []float64 {
+	_, buckets := w.Raw()
+	return buckets
+}
+
+// Header 返回窗口的元数据
+func (w *TimeWindow) Header() WindowHeader {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.header()
+}
+
+func (w *TimeWindow) header() WindowHeader {
+	return WindowHeader{
+		Size:       w.size,
+		Duration:   w.duration,
+		Cursor:     w.cursor,
+		LastTime:   w.lastTime,
+		LastUpdate: w.lastUpdate,
+	}
+}
+
+// NewTimeWindowFromRaw 由 Raw 返回的元数据和桶还原一个时间窗口
+// 桶会被复制，调用方可以继续复用传入的切片
+func NewTimeWindowFromRaw(header WindowHeader, buckets []float64, opts ...Option) (*TimeWindow, error) {
+	if header.Size <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", header.Size)
+	}
+	if header.Duration <= 0 {
+		return nil, fmt.Errorf("invalid bucket duration %s", header.Duration)
+	}
+	if err := checkLayout(header.Size, len(buckets), header.Cursor); err != nil {
+		return nil, err
+	}
+
+	w := NewTimeWindow(header.Size, header.Duration, opts...)
+	copy(w.buckets, buckets)
+	w.cursor = header.Cursor
+	w.lastTime = header.LastTime
+	w.lastUpdate = header.LastUpdate
+	w.inferWritten()
+	w.recompute()
+	return w, nil
+}
+
+// Restore 在一次加锁中用外部保存的状态替换窗口的桶，与 Raw 对应，适合从自定义的存储层还原窗口
+// buckets 按内部存储顺序排列，长度必须等于窗口的大小，不会改变窗口的大小和时间跨度，需要时先调用 Resize；
+// cursor 必须在 [0, size) 范围内。参数无效时返回错误，窗口保持不变。
+// 桶会被复制，是否被写入过按非零值推断；写入次数和 IncWeighted 的权重被清零，ElapsedBuckets 从此时重新计算
+func (w *TimeWindow) Restore(buckets []float64, cursor int, lastTime, lastUpdate time.Time) error {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if err := checkLayout(w.size, len(buckets), cursor); err != nil {
+		return err
+	}
+
+	copy(w.buckets, buckets)
+	w.cursor = cursor
+	w.lastTime = lastTime
+	w.lastUpdate = lastUpdate
+	w.createdAt = w.now()
+	clear(w.counts)
+	clear(w.weights)
+	w.inferWritten()
+	w.recompute()
+	return nil
+}
+
+// binaryVersion 是 MarshalBinary 编码格式的版本号
+const binaryVersion = 1
+
+// binaryHeaderLen 是二进制编码中桶之前的固定长度部分:
+// 版本(1) + 桶数量(4) + 时间跨度(8) + 游标(4) + 两个时间戳(各 8+4)
+const binaryHeaderLen = 1 + 4 + 8 + 4 + 12 + 12
+
+// MarshalBinary 实现 encoding.BinaryMarshaler 接口
+// 使用紧凑的定长小端序布局: 版本、桶数量、时间跨度、游标、上次更新时间、最近数据更新时间，
+// 随后是按存储顺序排列的桶。适合缓存等场景，SQL 存储仍使用 Value/Scan。
+func (w *TimeWindow) MarshalBinary() ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	buf := make([]byte, 0, binaryHeaderLen+8*w.size)
+	buf = append(buf, binaryVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(w.size))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(w.duration))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(w.cursor))
+	buf = appendTime(buf, w.lastTime)
+	buf = appendTime(buf, w.lastUpdate)
+	for _, v := range w.buckets {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler 接口，解码 MarshalBinary 的输出
+// 数据无效时返回错误，窗口保持不变
+func (w *TimeWindow) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderLen {
+		return fmt.Errorf("binary data too short: %d bytes", len(data))
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("unsupported binary version %d", data[0])
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[1:]))
+	duration := time.Duration(binary.LittleEndian.Uint64(data[5:]))
+	cursor := int(binary.LittleEndian.Uint32(data[13:]))
+	lastTime := readTime(data[17:])
+	lastUpdate := readTime(data[29:])
+
+	if size <= 0 {
+		return fmt.Errorf("invalid window size %d", size)
+	}
+	if cursor >= size {
+		return fmt.Errorf("cursor %d out of range [0,%d)", cursor, size)
+	}
+	if len(data) != binaryHeaderLen+8*size {
+		return fmt.Errorf("expected %d bytes for %d buckets, got %d", binaryHeaderLen+8*size, size, len(data))
+	}
+
+	buckets := make([]float64, size)
+	for i := range buckets {
+		buckets[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[binaryHeaderLen+8*i:]))
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.buckets = buckets
+	w.size = size
+	w.duration = duration
+	w.cursor = cursor
+	w.lastTime = lastTime
+	w.lastUpdate = lastUpdate
+	w.createdAt = w.now()
+	clear(w.counts)
+	clear(w.weights)
+	w.inferWritten()
+	w.recompute()
+
+	return nil
+}
+
+// appendTime 以秒(8字节)和纳秒(4字节)编码时间，可以表示零值时间
+func appendTime(buf []byte, t time.Time) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(t.Unix()))
+	return binary.LittleEndian.AppendUint32(buf, uint32(t.Nanosecond()))
+}
+
+func readTime(data []byte) time.Time {
+	sec := int64(binary.LittleEndian.Uint64(data))
+	nsec := int64(binary.LittleEndian.Uint32(data[8:]))
+	return time.Unix(sec, nsec)
+}
+
+// gobState 是 GobEncode 编码的窗口完整内部状态
+type gobState struct {
+	Buckets    []float64
+	Written    []bool
+	Size       int
+	Duration   time.Duration
+	LastTime   time.Time
+	Cursor     int
+	LastUpdate time.Time
+}
+
+// GobEncode 实现 gob.GobEncoder 接口，编码窗口的完整内部状态
+func (w *TimeWindow) GobEncode() ([]byte, error) {
+	w.mu.RLock()
+	state := gobState{
+		Buckets:    w.buckets,
+		Written:    w.written,
+		Size:       w.size,
+		Duration:   w.duration,
+		LastTime:   w.lastTime,
+		Cursor:     w.cursor,
+		LastUpdate: w.lastUpdate,
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(state)
+	w.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode 实现 gob.GobDecoder 接口，还原 GobEncode 编码的状态
+// 数据无效时返回错误，窗口保持不变
+func (w *TimeWindow) GobDecode(data []byte) error {
+	var state gobState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+		return fmt.Errorf("decode time window: %w", err)
+	}
+	if err := checkLayout(state.Size, len(state.Buckets), state.Cursor); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.buckets = state.Buckets
+	w.size = state.Size
+	w.duration = state.Duration
+	w.lastTime = state.LastTime
+	w.cursor = state.Cursor
+	w.lastUpdate = state.LastUpdate
+	w.createdAt = w.now()
+	clear(w.counts)
+	clear(w.weights)
+	if len(state.Written) == state.Size {
+		w.written = state.Written
+	} else {
+		w.inferWritten()
+	}
+	w.recompute()
+
+	return nil
+}
+
+// jsonVersion 是 MarshalJSON 输出的格式版本
+// 不带 version 字段的数据视为版本0，即引入版本号之前 Value 输出的格式，字段与版本1相同。
+// 修改格式时递增该版本，并在 migrateJSON 中加入从上一版本升级的步骤，使数据库中已有的旧数据仍能被 Scan 读取
+const jsonVersion = 1
+
+// jsonWindow 是 MarshalJSON 输出的公开格式:
+//
+//	{
+//	  "version": 1,
+//	  "buckets": [...],      // 按内部存储顺序排列的桶，需结合 cursor 解释
+//	  "written": [...],      // 每个桶是否被写入过，缺省时按非零值推断
+//	  "size": 60,            // 桶的数量
+//	  "duration": 1000000000, // 每个桶的时间跨度，单位纳秒
+//	  "last_time": "...",    // 当前桶的起始时间，RFC3339
+//	  "cursor": 0,           // 当前桶的位置
+//	  "last_update": "..."   // 最近一次数据更新时间，RFC3339
+//	}
+type jsonWindow struct {
+	Version    int           `json:"version"`
+	Buckets    []float64     `json:"buckets"`
+	Written    []bool        `json:"written,omitempty"`
+	Size       int           `json:"size"`
+	Duration   time.Duration `json:"duration"`
+	LastTime   time.Time     `json:"last_time"`
+	Cursor     int           `json:"cursor"`
+	LastUpdate time.Time     `json:"last_update"`
+}
+
+// MarshalJSON 实现 json.Marshaler 接口，输出带版本号的窗口状态，nil 窗口输出 null
+func (w *TimeWindow) MarshalJSON() ([]byte, error) {
+	if w == nil {
+		return []byte("null"), nil
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return json.Marshal(jsonWindow{
+		Version:    jsonVersion,
+		Buckets:    w.buckets,
+		Written:    w.written,
+		Size:       w.size,
+		Duration:   w.duration,
+		LastTime:   w.lastTime,
+		Cursor:     w.cursor,
+		LastUpdate: w.lastUpdate,
+	})
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，还原 MarshalJSON 输出的窗口状态
+// 解码失败、版本不受支持或桶数量、游标与窗口大小不一致时返回错误，不修改窗口；null 不做任何修改
+func (w *TimeWindow) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var state jsonWindow
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("unmarshal time window: %w", err)
+	}
+	if err := migrateJSON(&state); err != nil {
+		return err
+	}
+	if err := checkLayout(state.Size, len(state.Buckets), state.Cursor); err != nil {
+		return fmt.Errorf("unmarshal time window: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.buckets = state.Buckets
+	w.size = state.Size
+	w.duration = state.Duration
+	w.lastTime = state.LastTime
+	w.cursor = state.Cursor
+	w.lastUpdate = state.LastUpdate
+	w.createdAt = w.now()
+	clear(w.counts)
+	clear(w.weights)
+	if len(state.Written) == len(state.Buckets) {
+		w.written = state.Written
+	} else {
+		w.inferWritten()
+	}
+	w.recompute()
+
+	return nil
+}
+
+// checkLayout 检查解码得到的桶数量和游标与窗口大小是否一致
+func checkLayout(size, buckets, cursor int) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid window size %d", size)
+	}
+	if buckets != size {
+		return fmt.Errorf("expected %d buckets, got %d", size, buckets)
+	}
+	if cursor < 0 || cursor >= size {
+		return fmt.Errorf("cursor %d out of range [0,%d)", cursor, size)
+	}
+	return nil
+}
+
+// migrateJSON 将旧版本的数据逐个版本升级为当前版本，未知版本返回错误
+func migrateJSON(state *jsonWindow) error {
+	switch state.Version {
+	case 0:
+		// 版本0没有写入标记，由 UnmarshalJSON 按非零值推断
+		state.Written = nil
+		state.Version = 1
+		fallthrough
+	case jsonVersion:
+		return nil
+	default:
+		return fmt.Errorf("unsupported time window version %d (supported up to %d)", state.Version, jsonVersion)
+	}
+}
+
+// AggSnapshot 是窗口聚合值的快照，只保存报表需要的统计量而不保存桶，适合长期存储
+// 字段含义与 Sum、Avg、Count、Min、Max 和 LastUpdateTime 相同
+type AggSnapshot struct {
+	Sum        float64   `json:"sum"`
+	Avg        float64   `json:"avg"`
+	Count      int       `json:"count"`
+	Min        float64   `json:"min"`
+	Max        float64   `json:"max"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// AggregateSnapshot 先将窗口旋转到当前时间，然后在同一次加锁中计算各个聚合值
+func (w *TimeWindow) AggregateSnapshot() AggSnapshot {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	s := AggSnapshot{
+		Sum:        w.sum,
+		Count:      w.count(),
+		LastUpdate: w.lastUpdate,
+	}
+	if s.Count > 0 {
+		s.Avg = w.sum / float64(s.Count)
+	}
+	s.Min, s.Max = w.minMax()
+	return s
+}
@@ -0,0 +1,507 @@
+package hstat
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_RawRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Wrap the cursor around so raw order differs from age order
+	for i := 1; i <= 6; i++ {
+		w.Inc(float64(i))
+		clock.Add(time.Second)
+	}
+
+	header, buckets := w.Raw()
+	restored, err := NewTimeWindowFromRaw(header, buckets, WithClock(clock.Now))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if restored.Header() != w.Header() {
+		t.Errorf("Expected header %+v, got %+v", w.Header(), restored.Header())
+	}
+
+	want := w.GetData()
+	got := restored.GetData()
+	for i := range want {
+		if got[i].Values[0] != want[i].Values[0] {
+			t.Errorf("Bucket %d: expected %f, got %f", i, want[i].Values[0], got[i].Values[0])
+		}
+	}
+
+	// The restored window must not share the caller's slice
+	buckets[0] = 100
+	if restored.Sum() != w.Sum() {
+		t.Error("Restored window shares the raw buckets slice")
+	}
+}
+
+func TestNewTimeWindowFromRaw_Invalid(t *testing.T) {
+	header := WindowHeader{Size: 3, Duration: time.Second}
+
+	if _, err := NewTimeWindowFromRaw(header, make([]float64, 2)); err == nil {
+		t.Error("Expected error for buckets length mismatch")
+	}
+
+	header.Cursor = 3
+	if _, err := NewTimeWindowFromRaw(header, make([]float64, 3)); err == nil {
+		t.Error("Expected error for cursor out of range")
+	}
+
+	header = WindowHeader{Size: 0, Duration: time.Second}
+	if _, err := NewTimeWindowFromRaw(header, nil); err == nil {
+		t.Error("Expected error for zero size")
+	}
+}
+
+func TestTimeWindow_BinaryRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Wrap the cursor past the end of the buckets slice
+	for i := 1; i <= 6; i++ {
+		w.Inc(float64(i))
+		clock.Add(time.Second)
+	}
+
+	data, err := w.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data) != binaryHeaderLen+8*4 {
+		t.Errorf("Expected %d bytes, got %d", binaryHeaderLen+8*4, len(data))
+	}
+
+	var restored TimeWindow
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want, got := w.Header(), restored.Header()
+	if got.Size != want.Size || got.Duration != want.Duration || got.Cursor != want.Cursor ||
+		!got.LastTime.Equal(want.LastTime) || !got.LastUpdate.Equal(want.LastUpdate) {
+		t.Errorf("Expected header %+v, got %+v", want, got)
+	}
+	if restored.Sum() != w.Sum() {
+		t.Errorf("Expected sum %f, got %f", w.Sum(), restored.Sum())
+	}
+	for i, v := range w.RawBuckets() {
+		if restored.buckets[i] != v {
+			t.Errorf("Bucket %d: expected %f, got %f", i, v, restored.buckets[i])
+		}
+	}
+}
+
+func TestTimeWindow_BinaryZeroTimes(t *testing.T) {
+	w := NewTimeWindow(2, time.Second)
+	data, _ := w.MarshalBinary()
+
+	var restored TimeWindow
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !restored.LastUpdateTime().IsZero() {
+		t.Errorf("Expected zero last update time, got %v", restored.LastUpdateTime())
+	}
+}
+
+func TestTimeWindow_UnmarshalBinaryInvalid(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(1.0)
+	data, _ := w.MarshalBinary()
+
+	target := NewTimeWindow(2, time.Second)
+	target.Inc(7.0)
+
+	for name, bad := range map[string][]byte{
+		"short":     data[:10],
+		"truncated": data[:len(data)-1],
+		"version":   append([]byte{99}, data[1:]...),
+	} {
+		if err := target.UnmarshalBinary(bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if target.Sum() != 7.0 {
+		t.Errorf("Expected window to be unchanged after failed decode, got sum %f", target.Sum())
+	}
+}
+
+func TestTimeWindow_ScanRoundTrip(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(4.0)
+
+	value, err := w.Value()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var restored TimeWindow
+	if err := restored.Scan(value); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored.Sum() != 4.0 {
+		t.Errorf("Expected sum 4.0, got %f", restored.Sum())
+	}
+}
+
+func TestTimeWindow_ScanInvalidJSON(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(4.0)
+
+	if err := w.Scan([]byte("{not json")); err == nil {
+		t.Error("Expected error for corrupted data")
+	}
+
+	if w.Sum() != 4.0 || w.Header().Duration != time.Second {
+		t.Errorf("Expected window to be unchanged, got %+v sum %f", w.Header(), w.Sum())
+	}
+}
+
+func TestTimeWindow_GobRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Partially wrap the cursor and leave a written zero bucket
+	for i := 1; i <= 5; i++ {
+		w.Inc(float64(i))
+		clock.Add(time.Second)
+	}
+	w.Append(0)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(w); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored := NewTimeWindow(1, time.Minute, WithClock(clock.Now))
+	if err := gob.NewDecoder(&buf).Decode(restored); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if restored.Header().Cursor != w.Header().Cursor {
+		t.Errorf("Expected cursor %d, got %d", w.Header().Cursor, restored.Header().Cursor)
+	}
+	if !restored.Header().LastTime.Equal(w.Header().LastTime) ||
+		!restored.LastUpdateTime().Equal(w.LastUpdateTime()) {
+		t.Errorf("Expected timestamps to survive, got %+v", restored.Header())
+	}
+	if restored.Sum() != w.Sum() || restored.Count() != w.Count() {
+		t.Errorf("Expected sum %f count %d, got sum %f count %d",
+			w.Sum(), w.Count(), restored.Sum(), restored.Count())
+	}
+
+	want, got := w.GetData(), restored.GetData()
+	for i := range want {
+		if got[i].Values[0] != want[i].Values[0] {
+			t.Errorf("Bucket %d: expected %f, got %f", i, want[i].Values[0], got[i].Values[0])
+		}
+	}
+
+	// Both windows keep rotating identically
+	clock.Add(2 * time.Second)
+	if restored.Sum() != w.Sum() {
+		t.Errorf("Expected sums to stay equal after rotation, got %f and %f", w.Sum(), restored.Sum())
+	}
+}
+
+func TestTimeWindow_JSONRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(4.0)
+	clock.Add(time.Second)
+	w.Append(0)
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fields["version"] != float64(jsonVersion) {
+		t.Errorf("Expected version %d, got %v", jsonVersion, fields["version"])
+	}
+
+	restored := NewTimeWindow(1, time.Minute, WithClock(clock.Now))
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored.Sum() != 4.0 {
+		t.Errorf("Expected sum 4.0, got %f", restored.Sum())
+	}
+	// The written zero bucket survives the round trip
+	if restored.Count() != 2 {
+		t.Errorf("Expected count 2, got %d", restored.Count())
+	}
+}
+
+func TestTimeWindow_JSONNil(t *testing.T) {
+	var payload struct {
+		Window *TimeWindow `json:"window"`
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"window":null}` {
+		t.Errorf("Expected null window, got %s", data)
+	}
+
+	var w *TimeWindow
+	if data, err := w.MarshalJSON(); err != nil || string(data) != "null" {
+		t.Errorf("Expected null, got %s (%v)", data, err)
+	}
+}
+
+func TestTimeWindow_JSONVersions(t *testing.T) {
+	// Data written by Value before the version field existed is still accepted
+	legacy := []byte(`{"buckets":[1,0,2],"size":3,"duration":1000000000,"cursor":2}`)
+
+	var w TimeWindow
+	if err := w.UnmarshalJSON(legacy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if w.Sum() != 3.0 || w.Count() != 2 {
+		t.Errorf("Expected sum 3.0 count 2, got sum %f count %d", w.Sum(), w.Count())
+	}
+
+	future := []byte(`{"version":99,"buckets":[5],"size":1,"duration":1000000000}`)
+	if err := w.UnmarshalJSON(future); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+	if w.Sum() != 3.0 {
+		t.Errorf("Expected window to be unchanged, got sum %f", w.Sum())
+	}
+}
+
+func TestTimeWindow_AggregateSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	for _, v := range []float64{4, 0, -2, 6} {
+		if v != 0 {
+			w.Inc(v)
+		}
+		clock.Add(time.Second)
+	}
+	clock.Add(-time.Second)
+
+	s := w.AggregateSnapshot()
+	want := AggSnapshot{Sum: 8, Avg: 8.0 / 3, Count: 3, Min: -2, Max: 6, LastUpdate: clock.Now()}
+	if s != want {
+		t.Errorf("Expected %+v, got %+v", want, s)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded AggSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded != s {
+		t.Errorf("Expected %+v after round trip, got %+v", s, decoded)
+	}
+
+	// Expired buckets do not contribute
+	clock.Add(time.Hour)
+	if s := w.AggregateSnapshot(); s.Count != 0 || s.Sum != 0 {
+		t.Errorf("Expected an empty snapshot, got %+v", s)
+	}
+}
+
+func TestTimeWindow_ScanVersions(t *testing.T) {
+	cases := []struct {
+		name      string
+		blob      string
+		wantSum   float64
+		wantCount int
+	}{
+		// v0: written by Value before the version field existed
+		{"v0", `{"buckets":[1,0,2],"size":3,"duration":1000000000,"cursor":2}`, 3, 2},
+		// v1: written flags distinguish a written zero from an empty bucket
+		{"v1", `{"version":1,"buckets":[1,0,2],"written":[true,true,true],"size":3,"duration":1000000000,"cursor":2}`, 3, 3},
+	}
+	for _, c := range cases {
+		var w TimeWindow
+		if err := w.Scan([]byte(c.blob)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if w.Sum() != c.wantSum || w.Count() != c.wantCount {
+			t.Errorf("%s: expected sum %f count %d, got sum %f count %d",
+				c.name, c.wantSum, c.wantCount, w.Sum(), w.Count())
+		}
+		if h := w.Header(); h.Size != 3 || h.Duration != time.Second || h.Cursor != 2 {
+			t.Errorf("%s: unexpected header %+v", c.name, h)
+		}
+	}
+
+	var w TimeWindow
+	if err := w.Scan([]byte(`{"version":2,"buckets":[1],"size":1}`)); err == nil {
+		t.Error("Expected error for a future version")
+	}
+	if w.Header().Size != 0 {
+		t.Error("Expected the window to be left unpopulated")
+	}
+}
+
+func TestTimeWindow_ScanInconsistent(t *testing.T) {
+	blobs := map[string]string{
+		"too few buckets":  `{"version":1,"buckets":[1,2],"size":3,"duration":1000000000,"cursor":0}`,
+		"too many buckets": `{"version":1,"buckets":[1,2,3,4],"size":3,"duration":1000000000,"cursor":0}`,
+		"zero size":        `{"version":1,"buckets":[],"size":0,"duration":1000000000,"cursor":0}`,
+		"cursor past end":  `{"version":1,"buckets":[1,2,3],"size":3,"duration":1000000000,"cursor":3}`,
+		"negative cursor":  `{"buckets":[1,2,3],"size":3,"duration":1000000000,"cursor":-1}`,
+	}
+	for name, blob := range blobs {
+		w := NewTimeWindow(2, time.Second)
+		w.Inc(7.0)
+
+		if err := w.Scan([]byte(blob)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		// The window is left usable and unchanged
+		w.Inc(1.0)
+		if sum := w.Sum(); sum != 8.0 {
+			t.Errorf("%s: expected sum 8.0, got %f", name, sum)
+		}
+	}
+}
+
+func TestTimeWindow_Restore(t *testing.T) {
+	clock := newFakeClock()
+	src := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	for i := 1; i <= 6; i++ {
+		src.Inc(float64(i))
+		clock.Add(time.Second)
+	}
+	clock.Add(-time.Second)
+	header, buckets := src.Raw()
+
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	if err := w.Restore(buckets, header.Cursor, header.LastTime, header.LastUpdate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for age := 0; age < 4; age++ {
+		want, _ := src.GetValueAt(age)
+		if got, _ := w.GetValueAt(age); got != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, got)
+		}
+	}
+	if w.Sum() != src.Sum() || !w.LastUpdateTime().Equal(src.LastUpdateTime()) {
+		t.Errorf("Expected sum %f and last update %v, got %f and %v",
+			src.Sum(), src.LastUpdateTime(), w.Sum(), w.LastUpdateTime())
+	}
+
+	// The input is copied
+	buckets[0] = 100
+	if w.Sum() != src.Sum() {
+		t.Error("Expected Restore to copy the buckets")
+	}
+}
+
+func TestTimeWindow_RestoreInvalid(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(5.0)
+
+	if err := w.Restore([]float64{1, 2}, 0, time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for a bucket count mismatch")
+	}
+	if err := w.Restore([]float64{1, 2, 3}, 3, time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for a cursor out of range")
+	}
+	if sum := w.Sum(); sum != 5.0 {
+		t.Errorf("Expected the window to be unchanged, got sum %f", sum)
+	}
+}
+
+func TestTimeWindow_DecodeResetsElapsedBuckets(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(60, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+	clock.Add(time.Hour)
+
+	binaryData, err := w.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	jsonData, err := json.Marshal(w)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(w); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoders := map[string]func(*TimeWindow) error{
+		"binary": func(r *TimeWindow) error { return r.UnmarshalBinary(binaryData) },
+		"json":   func(r *TimeWindow) error { return json.Unmarshal(jsonData, r) },
+		"gob":    func(r *TimeWindow) error { return gob.NewDecoder(bytes.NewReader(gobData.Bytes())).Decode(r) },
+		"scan":   func(r *TimeWindow) error { return r.Scan(jsonData) },
+		"restore": func(r *TimeWindow) error {
+			header, buckets := w.Raw()
+			return r.Restore(buckets, header.Cursor, header.LastTime, header.LastUpdate)
+		},
+	}
+	for name, decode := range decoders {
+		// Created an hour ago, so it has fully warmed up before decoding
+		r := NewTimeWindow(60, time.Second, WithClock(clock.Now))
+		r.createdAt = clock.Now().Add(-time.Hour)
+		if err := decode(r); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if n := r.ElapsedBuckets(); n != 1 {
+			t.Errorf("%s: expected 1 elapsed bucket after decoding, got %d", name, n)
+		}
+	}
+}
+
+func TestTimeWindow_DecodeClearsSampleCounts(t *testing.T) {
+	src := NewTimeWindow(4, time.Second)
+	src.Inc(10)
+
+	binaryData, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	jsonData, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(src); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoders := map[string]func(*TimeWindow) error{
+		"binary": func(r *TimeWindow) error { return r.UnmarshalBinary(binaryData) },
+		"json":   func(r *TimeWindow) error { return json.Unmarshal(jsonData, r) },
+		"gob":    func(r *TimeWindow) error { return gob.NewDecoder(bytes.NewReader(gobData.Bytes())).Decode(r) },
+	}
+	for name, decode := range decoders {
+		// Counts from before decoding must not leak into the decoded window
+		r := NewTimeWindow(4, time.Second, WithSampleCounts())
+		for i := 0; i < 100; i++ {
+			r.Inc(1)
+		}
+		if err := decode(r); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if n := r.SampleCount(); n != 0 {
+			t.Errorf("%s: expected sample count 0 after decoding, got %d", name, n)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package hstat
+
+import (
+	"fmt"
+	"time"
+)
+
+// Blend 按权重混合两个时间窗口，返回一个新窗口
+// 新窗口中每个桶的值为 alpha*w + (1-alpha)*other，两个窗口的桶按年龄对齐。
+// 两个窗口的桶数量和时间跨度必须一致，alpha 必须在 [0,1] 之间。
+// 只有一侧有数据的桶，另一侧按 0 参与计算，即结果为该侧值乘以对应的权重；
+// 任一侧被写入过的桶在结果中都视为被写入过。
+func (w *TimeWindow) Blend(other *TimeWindow, alpha float64) (*TimeWindow, error) {
+	if alpha < 0 || alpha > 1 {
+		return nil, fmt.Errorf("alpha must be in [0,1], got %v", alpha)
+	}
+	if err := w.checkGeometry(other); err != nil {
+		return nil, err
+	}
+
+	now := w.now()
+	a := w.byAge(now)
+	b := other.byAge(now)
+
+	for i := range a.values {
+		a.values[i] = alpha*a.values[i] + (1-alpha)*b.values[i]
+		a.written[i] = a.written[i] || b.written[i]
+	}
+	a.lastUpdate = laterTime(a.lastUpdate, b.lastUpdate)
+
+	return w.newByAge(a), nil
+}
+
+// Merge 将另一个窗口的数据按年龄对齐累加到当前窗口
+// 两个窗口先旋转到同一时间，再把 other 中年龄为 i 的桶加到当前窗口年龄为 i 的桶上，
+// 因此即使两个窗口的游标位置不同也能正确对齐。桶数量或时间跨度不一致时返回错误。
+// 与 Inc 等写入一样会触发 OnUpdate 回调和阈值告警；启用 WithSampleCounts 时累加 other 的写入次数，
+// other 未启用时每个合并的桶计一次
+func (w *TimeWindow) Merge(other *TimeWindow) error {
+	if err := w.checkGeometry(other); err != nil {
+		return err
+	}
+
+	now := w.now()
+	src := other.byAge(now)
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(now)
+	merged := false
+	for age, v := range src.values {
+		if !src.written[age] {
+			continue
+		}
+		idx := (w.cursor - age + w.size) % w.size
+		w.setBucket(idx, w.buckets[idx]+v)
+		if src.counts != nil {
+			w.countSamples(idx, src.counts[age])
+		} else {
+			w.countSamples(idx, 1)
+		}
+		merged = true
+	}
+	w.lastUpdate = laterTime(w.lastUpdate, src.lastUpdate)
+	if merged {
+		w.markUpdated()
+	}
+	return nil
+}
+
+// Combine 将多个窗口按年龄对齐累加为一个新窗口，输入的窗口保持不变
+// 所有窗口的桶数量和时间跨度必须一致，新窗口使用第一个窗口的时钟，对齐方式与 Merge 相同。
+// 没有传入窗口、含有 nil 或形状不一致时返回错误
+func Combine(windows ...*TimeWindow) (*TimeWindow, error) {
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no windows to combine")
+	}
+	first := windows[0]
+	if first == nil {
+		return nil, fmt.Errorf("window 0 is nil")
+	}
+	for i, other := range windows[1:] {
+		if err := first.checkGeometry(other); err != nil {
+			return nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+
+	now := first.now()
+	result := first.newByAge(first.byAge(now))
+	for _, other := range windows[1:] {
+		if err := result.Merge(other); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Diff 返回当前窗口与较早的快照之间逐桶的差值，下标为年龄，0为当前桶
+// 两个窗口先旋转到同一时间再按年龄对齐，因此即使快照之后游标已经前进，同一时间段的桶仍然一一对应；
+// 快照中已经过期的桶按 0 计算。桶数量或时间跨度不一致时返回错误。
+func (w *TimeWindow) Diff(earlier *TimeWindow) ([]float64, error) {
+	if err := w.checkGeometry(earlier); err != nil {
+		return nil, err
+	}
+
+	now := w.now()
+	cur := w.byAge(now)
+	prev := earlier.byAge(now)
+
+	diff := make([]float64, len(cur.values))
+	for i := range diff {
+		diff[i] = cur.values[i] - prev.values[i]
+	}
+	return diff, nil
+}
+
+// Rollup 将窗口降采样为一个更粗粒度的新窗口
+// 按年龄把每 factor 个相邻的桶聚合为新窗口的一个桶，新窗口有 size/factor 个桶，每个桶的时间跨度为 factor*duration；
+// 新窗口的当前桶由原窗口的当前桶和它之前的 factor-1 个桶聚合而成。
+// mode 为 AggSum、AggAvg、AggMax 或 AggLast(取最新的值)，只有被写入过的桶参与聚合，
+// 组内有任一桶被写入过时新桶视为被写入过。factor <= 0 或 size 不能被 factor 整除时返回错误。
+func (w *TimeWindow) Rollup(factor int, mode AggMode) (*TimeWindow, error) {
+	if factor <= 0 {
+		return nil, fmt.Errorf("factor must be positive, got %d", factor)
+	}
+	now := w.now()
+	src := w.byAge(now)
+	if len(src.values)%factor != 0 {
+		return nil, fmt.Errorf("window size %d is not divisible by factor %d", len(src.values), factor)
+	}
+
+	groups := len(src.values) / factor
+	dst := ageView{
+		values:     make([]float64, groups),
+		written:    make([]bool, groups),
+		duration:   src.duration * time.Duration(factor),
+		lastTime:   src.lastTime.Add(-time.Duration(factor-1) * src.duration),
+		lastUpdate: src.lastUpdate,
+	}
+	for g := 0; g < groups; g++ {
+		var acc float64
+		count := 0
+		for age := g * factor; age < (g+1)*factor; age++ {
+			if !src.written[age] {
+				continue
+			}
+			v := src.values[age]
+			switch {
+			case count == 0:
+				acc = v
+			case mode == AggMax:
+				acc = max(acc, v)
+			case mode == AggLast:
+				// 年龄从小到大遍历，第一个被写入的桶即为最新的值
+			default:
+				acc += v
+			}
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		if mode == AggAvg {
+			acc /= float64(count)
+		}
+		dst.values[g] = acc
+		dst.written[g] = true
+	}
+
+	return w.newByAge(dst), nil
+}
+
+// checkGeometry 检查两个窗口的桶数量和时间跨度是否一致
+func (w *TimeWindow) checkGeometry(other *TimeWindow) error {
+	if other == nil {
+		return fmt.Errorf("other window is nil")
+	}
+
+	w.mu.RLock()
+	size, duration := w.size, w.duration
+	w.mu.RUnlock()
+
+	other.mu.RLock()
+	otherSize, otherDuration := other.size, other.duration
+	other.mu.RUnlock()
+
+	if size != otherSize || duration != otherDuration {
+		return fmt.Errorf("window geometry mismatch: size %d vs %d, duration %s vs %s",
+			size, otherSize, duration, otherDuration)
+	}
+	return nil
+}
+
+// ageView 是按年龄排列的窗口数据副本，下标0为当前桶
+type ageView struct {
+	values     []float64
+	written    []bool
+	counts     []int // 每个桶的写入次数，未启用 WithSampleCounts 时为 nil
+	duration   time.Duration
+	lastTime   time.Time // 当前桶的起始时间
+	lastUpdate time.Time
+}
+
+// byAge 将窗口旋转到 now 后按年龄复制桶的数据
+func (w *TimeWindow) byAge(now time.Time) ageView {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(now)
+
+	v := ageView{
+		values:     make([]float64, w.size),
+		written:    make([]bool, w.size),
+		duration:   w.duration,
+		lastTime:   w.lastTime,
+		lastUpdate: w.lastUpdate,
+	}
+	if w.counts != nil {
+		v.counts = make([]int, w.size)
+	}
+	for i := 0; i < w.size; i++ {
+		idx := (w.cursor - i + w.size) % w.size
+		v.values[i] = w.buckets[idx]
+		v.written[i] = w.written[idx]
+		if v.counts != nil {
+			v.counts[i] = w.counts[idx]
+		}
+	}
+	return v
+}
+
+// newByAge 由按年龄排列的数据构造一个与 w 时钟相同的新窗口，游标位于0
+// 当前桶的起始时间取自 v，使新窗口的桶边界与来源窗口一致
+func (w *TimeWindow) newByAge(v ageView) *TimeWindow {
+	size := len(v.values)
+	result := NewTimeWindow(size, v.duration, WithClock(w.clock))
+	result.lastTime = v.lastTime
+	result.lastUpdate = v.lastUpdate
+	for i := range v.values {
+		result.buckets[(size-i)%size] = v.values[i]
+		result.written[(size-i)%size] = v.written[i]
+	}
+	result.recompute()
+	return result
+}
+
+func laterTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,297 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_Blend(t *testing.T) {
+	current := NewTimeWindow(5, time.Second)
+	baseline := NewTimeWindow(5, time.Second)
+
+	current.Inc(10.0)
+	baseline.Inc(20.0)
+
+	blended, err := current.Blend(baseline, 0.25)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if val, _ := blended.GetLatestValue(); val != 17.5 {
+		t.Errorf("Expected value 17.5, got %f", val)
+	}
+
+	// The inputs must be left untouched
+	if val, _ := current.GetLatestValue(); val != 10.0 {
+		t.Errorf("Expected current value 10.0, got %f", val)
+	}
+}
+
+func TestTimeWindow_BlendOneSided(t *testing.T) {
+	current := NewTimeWindow(5, time.Second)
+	baseline := NewTimeWindow(5, time.Second)
+
+	baseline.Inc(8.0)
+
+	blended, err := current.Blend(baseline, 0.5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sum := blended.Sum(); sum != 4.0 {
+		t.Errorf("Expected sum 4.0, got %f", sum)
+	}
+}
+
+func TestTimeWindow_BlendInvalid(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+
+	if _, err := w.Blend(NewTimeWindow(5, time.Second), 1.5); err == nil {
+		t.Error("Expected error for alpha out of range")
+	}
+
+	if _, err := w.Blend(NewTimeWindow(6, time.Second), 0.5); err == nil {
+		t.Error("Expected error for size mismatch")
+	}
+
+	if _, err := w.Blend(NewTimeWindow(5, time.Minute), 0.5); err == nil {
+		t.Error("Expected error for duration mismatch")
+	}
+}
+
+func TestTimeWindow_Merge(t *testing.T) {
+	clock := newFakeClock()
+	a := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Advance a's cursor so the two windows are out of phase
+	a.Inc(1.0)
+	clock.Add(time.Second)
+	a.Inc(2.0)
+	clock.Add(time.Second)
+
+	b := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	b.Inc(10.0)
+	a.Inc(3.0)
+	clock.Add(time.Second)
+	b.Inc(20.0)
+	a.Inc(4.0)
+
+	if a.Header().Cursor == b.Header().Cursor {
+		t.Fatal("Expected windows with different cursor positions")
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Ages 0..3 hold 4+20, 3+10, 2, 1
+	for age, want := range []float64{24, 13, 2, 1} {
+		if val, _ := a.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+	if sum := a.Sum(); sum != 40 {
+		t.Errorf("Expected sum 40, got %f", sum)
+	}
+
+	// The source window is left untouched
+	if sum := b.Sum(); sum != 30 {
+		t.Errorf("Expected source sum 30, got %f", sum)
+	}
+}
+
+func TestTimeWindow_MergeNotifies(t *testing.T) {
+	w := NewTimeWindow(4, time.Second, WithSampleCounts())
+	ch := make(chan float64, 10)
+	w.SetThreshold(10, Rising, ch)
+	var updates []float64
+	w.OnUpdate(func(v float64) { updates = append(updates, v) })
+
+	other := NewTimeWindow(4, time.Second, WithSampleCounts())
+	other.Inc(8)
+	other.Inc(4)
+
+	if err := w.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(updates) != 1 || updates[0] != 12 {
+		t.Errorf("Expected one update with 12, got %v", updates)
+	}
+	if got := drain(ch); len(got) != 1 || got[0] != 12 {
+		t.Errorf("Expected alert [12], got %v", got)
+	}
+	if n := w.SampleCount(); n != 2 {
+		t.Errorf("Expected the source's 2 samples, got %d", n)
+	}
+
+	// Merging an empty window does not notify
+	if err := w.Merge(NewTimeWindow(4, time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Errorf("Expected no update for an empty merge, got %v", updates)
+	}
+}
+
+func TestTimeWindow_MergeMismatch(t *testing.T) {
+	w := NewTimeWindow(4, time.Second)
+
+	if err := w.Merge(NewTimeWindow(5, time.Second)); err == nil {
+		t.Error("Expected error for size mismatch")
+	}
+	if err := w.Merge(NewTimeWindow(4, time.Minute)); err == nil {
+		t.Error("Expected error for duration mismatch")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	clock := newFakeClock()
+	a := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	a.Inc(1.0)
+	clock.Add(time.Second)
+
+	// Each window starts one bucket later, so the cursors all differ
+	b := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	a.Inc(2.0)
+	b.Inc(10.0)
+	clock.Add(time.Second)
+
+	c := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	a.Inc(3.0)
+	b.Inc(20.0)
+	c.Inc(100.0)
+
+	combined, err := Combine(a, b, c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Ages 0..3 hold 3+20+100, 2+10, 1, nothing
+	for age, want := range []float64{123, 12, 1, 0} {
+		if val, _ := combined.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+	if sum := a.Sum(); sum != 6 {
+		t.Errorf("Expected input sum 6, got %f", sum)
+	}
+}
+
+func TestCombineBucketBoundaries(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+	clock.Add(500 * time.Millisecond)
+	w.Inc(1.0)
+
+	derived := map[string]func() (*TimeWindow, error){
+		"Combine": func() (*TimeWindow, error) { return Combine(w) },
+		"Blend":   func() (*TimeWindow, error) { return w.Blend(w, 0.5) },
+		"Rollup":  func() (*TimeWindow, error) { return w.Rollup(1, AggSum) },
+	}
+	results := make(map[string]*TimeWindow)
+	for name, derive := range derived {
+		r, err := derive()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		results[name] = r
+	}
+
+	// The source rotates 0.6s later; derived windows must rotate with it
+	clock.Add(600 * time.Millisecond)
+	if val, _ := w.GetValueAt(1); val != 2.0 {
+		t.Fatalf("Expected the source to hold 2.0 at age 1, got %f", val)
+	}
+	for name, r := range results {
+		if val, _ := r.GetValueAt(1); val != 2.0 {
+			t.Errorf("%s: expected 2.0 at age 1 after the source rotated, got %f", name, val)
+		}
+		if r.Header().LastTime != w.Header().LastTime {
+			t.Errorf("%s: expected bucket start %v, got %v", name, w.Header().LastTime, r.Header().LastTime)
+		}
+	}
+}
+
+func TestCombineMismatch(t *testing.T) {
+	w := NewTimeWindow(4, time.Second)
+
+	if _, err := Combine(); err == nil {
+		t.Error("Expected error for no windows")
+	}
+	if _, err := Combine(w, NewTimeWindow(4, time.Second), NewTimeWindow(5, time.Second)); err == nil {
+		t.Error("Expected error for size mismatch")
+	}
+	if _, err := Combine(w, nil); err == nil {
+		t.Error("Expected error for nil window")
+	}
+}
+
+func TestTimeWindow_Diff(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	snapshot := w.Clone()
+
+	// Advance the cursor after the snapshot and update both old and new slots
+	clock.Add(time.Second)
+	w.Inc(5.0)
+	if err := w.IncAt(clock.Now().Add(-time.Second), 3.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	diff, err := w.Diff(snapshot)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Ages 0..3: new slot, the slot current at snapshot time, the oldest slot, empty
+	for age, want := range []float64{5, 3, 0, 0} {
+		if diff[age] != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, diff[age])
+		}
+	}
+
+	if _, err := w.Diff(NewTimeWindow(5, time.Second)); err == nil {
+		t.Error("Expected error for size mismatch")
+	}
+}
+
+func TestTimeWindow_Rollup(t *testing.T) {
+	// Oldest to newest; the zero is an unwritten gap
+	w := newFilledWindow(newFakeClock(), 1, 5, 0, 3, 6, 2)
+
+	cases := []struct {
+		mode AggMode
+		want []float64 // by age in the rolled up window
+	}{
+		{AggSum, []float64{8, 3, 6}},
+		{AggAvg, []float64{4, 3, 3}},
+		{AggMax, []float64{6, 3, 5}},
+		{AggLast, []float64{2, 3, 5}},
+	}
+	for _, c := range cases {
+		r, err := w.Rollup(2, c.mode)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if h := r.Header(); h.Size != 3 || h.Duration != 2*time.Second {
+			t.Errorf("Mode %d: expected 3 buckets of 2s, got %d of %s", c.mode, h.Size, h.Duration)
+		}
+		for age, want := range c.want {
+			if val, _ := r.GetValueAt(age); val != want {
+				t.Errorf("Mode %d age %d: expected %f, got %f", c.mode, age, want, val)
+			}
+		}
+	}
+
+	if _, err := w.Rollup(4, AggSum); err == nil {
+		t.Error("Expected error for size not divisible by factor")
+	}
+	if _, err := w.Rollup(0, AggSum); err == nil {
+		t.Error("Expected error for non-positive factor")
+	}
+}
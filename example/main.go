@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"pkg.blksails.net/x/hstat"
+	"pkg.blksails.net/x/hstat/hstatterm"
 )
 
 // 模拟用户活动
@@ -47,7 +48,8 @@ func displayStats(window *hstat.TimeWindow, done chan struct{}) {
 	defer ticker.Stop()
 
 	opt := &hstat.HistogramOption{
-		Height: 15,
+		Height:    15,
+		WidthFunc: hstatterm.WidthFunc(os.Stdout), // 随终端宽度调整，避免换行
 	}
 
 	clearScreen := "\033[H\033[2J"
@@ -58,20 +60,16 @@ func displayStats(window *hstat.TimeWindow, done chan struct{}) {
 			fmt.Print(clearScreen)
 
 			now := time.Now()
-			lastUpdate := window.LastUpdateTime()
 			total := window.Sum()
 
 			// 计算最近更新距现在的时间
 			var timeSinceUpdate string
-			if !lastUpdate.IsZero() {
-				duration := now.Sub(lastUpdate)
-				if duration < time.Second {
-					timeSinceUpdate = "刚刚"
-				} else {
-					timeSinceUpdate = fmt.Sprintf("%.1f秒前", duration.Seconds())
-				}
-			} else {
+			if window.LastUpdateTime().IsZero() {
 				timeSinceUpdate = "暂无数据"
+			} else if !window.IsStale(time.Second) {
+				timeSinceUpdate = "刚刚"
+			} else {
+				timeSinceUpdate = fmt.Sprintf("%.1f秒前", window.StaleFor().Seconds())
 			}
 
 			// 显示标题和统计信息
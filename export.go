@@ -0,0 +1,180 @@
+package hstat
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteCSV 将窗口数据以 CSV 格式写入 out，每个桶一行，按从旧到新排列
+// 表头为 time,time_offset_seconds,value：time 为桶的绝对时间(RFC 3339)，
+// time_offset_seconds 为相对当前桶的秒数(当前桶为0，更早的桶为负数)
+func (w *TimeWindow) WriteCSV(out io.Writer) error {
+	now := w.now()
+	view := w.byAge(now)
+	values, duration := view.values, view.duration
+
+	cw := csv.NewWriter(out)
+	if err := cw.Write([]string{"time", "time_offset_seconds", "value"}); err != nil {
+		return err
+	}
+	for age := len(values) - 1; age >= 0; age-- {
+		offset := -time.Duration(age) * duration
+		record := []string{
+			now.Add(offset).Format(time.RFC3339Nano),
+			strconv.FormatFloat(offset.Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(values[age], 'f', -1, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SVGOption 用于配置 SVG 走势图
+type SVGOption struct {
+	Width    int    // 图像宽度（如果 <= 0，则使用默认值100）
+	Height   int    // 图像高度（如果 <= 0，则使用默认值20）
+	Stroke   string // 线条颜色（为空时使用 currentColor）
+	Fill     bool   // 是否填充折线下方的区域
+	Baseline bool   // 是否绘制最小值和最大值的水平参考线
+}
+
+// DefaultSVGOption 返回默认的 SVG 走势图配置
+func DefaultSVGOption() *SVGOption {
+	return &SVGOption{
+		Width:  100,
+		Height: 20,
+		Stroke: "currentColor",
+	}
+}
+
+// RenderSVG 将窗口数据渲染为一个内联 SVG 走势图，折线按从旧到新从左到右排列
+// 纵轴按窗口内的最小值和最大值缩放；所有值相等(包括全为0)时绘制一条居中的水平线
+func (w *TimeWindow) RenderSVG(opt *SVGOption) string {
+	if opt == nil {
+		opt = DefaultSVGOption()
+	}
+	width, height := opt.Width, opt.Height
+	if width <= 0 {
+		width = 100
+	}
+	if height <= 0 {
+		height = 20
+	}
+	stroke := opt.Stroke
+	if stroke == "" {
+		stroke = "currentColor"
+	}
+	stroke = html.EscapeString(stroke)
+
+	values := w.byAge(w.now()).values
+	n := len(values)
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	y := func(v float64) float64 {
+		if hi == lo {
+			return float64(height) / 2
+		}
+		return float64(height) - (v-lo)/(hi-lo)*float64(height)
+	}
+
+	var points strings.Builder
+	for i := 0; i < n; i++ {
+		x := 0.0
+		if n > 1 {
+			x = float64(i) * float64(width) / float64(n-1)
+		}
+		if i > 0 {
+			points.WriteByte(' ')
+		}
+		fmt.Fprintf(&points, "%.2f,%.2f", x, y(values[n-1-i]))
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	if opt.Fill {
+		fmt.Fprintf(&result, `<polygon points="0,%d %s %d,%d" fill="%s" fill-opacity="0.2" stroke="none"/>`,
+			height, points.String(), width, height, stroke)
+	}
+	if opt.Baseline {
+		for _, v := range []float64{lo, hi} {
+			fmt.Fprintf(&result, `<line x1="0" y1="%.2f" x2="%d" y2="%.2f" stroke="%s" stroke-opacity="0.4" stroke-dasharray="2,2"/>`,
+				y(v), width, y(v), stroke)
+		}
+	}
+	fmt.Fprintf(&result, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1"/>`, points.String(), stroke)
+	result.WriteString("</svg>")
+	return result.String()
+}
+
+// MarkdownOption 用于配置 Markdown 表格
+type MarkdownOption struct {
+	TimeFormat string // 时间列的格式(参见 time.Layout)，为空时使用 time.RFC3339
+	Sparkline  bool   // 是否追加一列用 Unicode 方块字符表示的走势
+}
+
+// sparkBlocks 为走势列使用的方块字符，从低到高排列
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// RenderMarkdownTable 将窗口数据渲染为 Markdown 表格，每个桶一行，按从旧到新排列
+// 表头为 | time | value |，启用 Sparkline 时追加 spark 列，方块高度按窗口内有限值的最小值和最大值缩放，
+// 非有限值(±Inf、NaN)显示为最低的方块。
+// opt 为 nil 时使用默认配置
+func (w *TimeWindow) RenderMarkdownTable(opt *MarkdownOption) string {
+	if opt == nil {
+		opt = &MarkdownOption{}
+	}
+	layout := opt.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	now := w.now()
+	view := w.byAge(now)
+	values, duration := view.values, view.duration
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			lo = min(lo, v)
+			hi = max(hi, v)
+		}
+	}
+	spark := func(v float64) string {
+		if !(hi > lo) || math.IsInf(v, 0) || math.IsNaN(v) {
+			return string(sparkBlocks[0])
+		}
+		i := int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		return string(sparkBlocks[min(max(i, 0), len(sparkBlocks)-1)])
+	}
+
+	var result strings.Builder
+	if opt.Sparkline {
+		result.WriteString("| time | value | spark |\n| --- | ---: | --- |\n")
+	} else {
+		result.WriteString("| time | value |\n| --- | ---: |\n")
+	}
+	for age := len(values) - 1; age >= 0; age-- {
+		ts := now.Add(-time.Duration(age) * duration).Format(layout)
+		value := strconv.FormatFloat(values[age], 'f', -1, 64)
+		if opt.Sparkline {
+			fmt.Fprintf(&result, "| %s | %s | %s |\n", ts, value, spark(values[age]))
+		} else {
+			fmt.Fprintf(&result, "| %s | %s |\n", ts, value)
+		}
+	}
+	return result.String()
+}
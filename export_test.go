@@ -0,0 +1,115 @@
+package hstat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_WriteCSV(t *testing.T) {
+	clock := newFakeClock()
+	w := newFilledWindow(clock, 1, 2.5, 0, 4)
+
+	var buf bytes.Buffer
+	if err := w.WriteCSV(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Expected header plus 4 rows, got %d", len(records))
+	}
+	if h := records[0]; h[0] != "time" || h[1] != "time_offset_seconds" || h[2] != "value" {
+		t.Errorf("Unexpected header %v", h)
+	}
+
+	// Rows run oldest to newest
+	for i, want := range []float64{1, 2.5, 0, 4} {
+		row := records[i+1]
+		if v, _ := strconv.ParseFloat(row[2], 64); v != want {
+			t.Errorf("Row %d: expected value %f, got %s", i, want, row[2])
+		}
+		if off, _ := strconv.ParseFloat(row[1], 64); off != float64(i-3) {
+			t.Errorf("Row %d: expected offset %d, got %s", i, i-3, row[1])
+		}
+		ts, err := time.Parse(time.RFC3339Nano, row[0])
+		if err != nil || !ts.Equal(clock.Now().Add(time.Duration(i-3)*time.Second)) {
+			t.Errorf("Row %d: unexpected time %s", i, row[0])
+		}
+	}
+}
+
+func TestTimeWindow_RenderSVG(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 0, 5, 10)
+
+	svg := w.RenderSVG(&SVGOption{Width: 100, Height: 10, Stroke: "red"})
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Fatalf("Expected an svg element, got %q", svg)
+	}
+
+	// Oldest to newest, scaled between min 0 and max 10
+	if !strings.Contains(svg, `points="0.00,10.00 50.00,5.00 100.00,0.00"`) {
+		t.Errorf("Unexpected polyline in %q", svg)
+	}
+	if !strings.Contains(svg, `stroke="red"`) {
+		t.Errorf("Expected stroke color in %q", svg)
+	}
+	if strings.Contains(svg, "<polygon") || strings.Contains(svg, "<line") {
+		t.Errorf("Expected no fill or baselines by default, got %q", svg)
+	}
+
+	svg = w.RenderSVG(&SVGOption{Width: 100, Height: 10, Fill: true, Baseline: true})
+	if !strings.Contains(svg, "<polygon") || strings.Count(svg, "<line") != 2 {
+		t.Errorf("Expected fill and two baselines, got %q", svg)
+	}
+}
+
+func TestTimeWindow_RenderSVGFlat(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+
+	svg := w.RenderSVG(&SVGOption{Width: 10, Height: 10})
+	if !strings.Contains(svg, `points="0.00,5.00 5.00,5.00 10.00,5.00"`) {
+		t.Errorf("Expected a flat line for an empty window, got %q", svg)
+	}
+}
+
+func TestTimeWindow_RenderMarkdownTableNonFinite(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, math.NaN(), 5, math.Inf(1))
+
+	lines := strings.Split(w.RenderMarkdownTable(&MarkdownOption{Sparkline: true}), "\n")
+	for i, want := range []string{" ▁ |", " ▁ |", " █ |", " ▁ |"} {
+		if !strings.HasSuffix(lines[2+i], want) {
+			t.Errorf("Row %d: expected suffix %q, got %q", i, want, lines[2+i])
+		}
+	}
+}
+
+func TestTimeWindow_RenderMarkdownTable(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 0, 1.5, 10)
+
+	lines := strings.Split(strings.TrimSuffix(w.RenderMarkdownTable(nil), "\n"), "\n")
+	if len(lines) != 3+2 {
+		t.Fatalf("Expected header, separator and 3 rows, got %d lines", len(lines))
+	}
+	if lines[0] != "| time | value |" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], " | 0 |") || !strings.HasSuffix(lines[4], " | 10 |") {
+		t.Errorf("Expected rows oldest to newest, got %q and %q", lines[2], lines[4])
+	}
+
+	lines = strings.Split(strings.TrimSuffix(w.RenderMarkdownTable(&MarkdownOption{Sparkline: true}), "\n"), "\n")
+	if lines[0] != "| time | value | spark |" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], " ▁ |") || !strings.HasSuffix(lines[4], " █ |") {
+		t.Errorf("Expected sparkline scaled between min and max, got %q and %q", lines[2], lines[4])
+	}
+}
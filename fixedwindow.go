@@ -0,0 +1,107 @@
+package hstat
+
+import "time"
+
+// FixedBuckets 限定 FixedWindow 可用的桶数组类型
+// Go 泛型不支持常量参数，因此用数组类型本身来表达桶的数量
+type FixedBuckets interface {
+	~[4]float64 | ~[8]float64 | ~[16]float64 | ~[32]float64 | ~[64]float64
+}
+
+// FixedWindow 表示一个桶数量在编译期确定的时间窗口
+// 桶存放在定长数组中，可以直接分配在栈上或内嵌在其他结构体中，
+// 适用于按请求创建的小型短生命周期窗口。零值可用，桶时间跨度默认为5分钟。
+// 为避免互斥锁导致逃逸到堆上，FixedWindow 不是并发安全的，只应在单个 goroutine 中使用。
+//
+// 用法: var w hstat.FixedWindow[[8]float64]
+type FixedWindow[B FixedBuckets] struct {
+	buckets  B             // 定长桶数组
+	duration time.Duration // 每个桶的时间跨度
+	lastTime time.Time     // 上次更新时间
+	cursor   int           // 当前桶的位置
+}
+
+// NewFixedWindow 创建一个新的定长时间窗口
+// duration: 每个桶的时间跨度
+func NewFixedWindow[B FixedBuckets](duration time.Duration) FixedWindow[B] {
+	return FixedWindow[B]{
+		duration: duration,
+		lastTime: time.Now(),
+	}
+}
+
+// Inc 在当前时间窗口中累加值
+func (w *FixedWindow[B]) Inc(delta float64) {
+	w.rotate(time.Now())
+	w.buckets[w.cursor] += delta
+}
+
+// rotate 根据时间推移调整窗口
+func (w *FixedWindow[B]) rotate(now time.Time) {
+	if w.duration <= 0 {
+		w.duration = defaultDuration
+	}
+	if w.lastTime.IsZero() {
+		w.lastTime = now
+		return
+	}
+	passed := int(now.Sub(w.lastTime) / w.duration)
+	if passed <= 0 {
+		return
+	}
+
+	size := len(w.buckets)
+	if passed >= size {
+		for i := 0; i < size; i++ {
+			w.buckets[i] = 0
+		}
+		w.cursor = 0
+	} else {
+		for i := 0; i < passed; i++ {
+			w.cursor = (w.cursor + 1) % size
+			w.buckets[w.cursor] = 0
+		}
+	}
+
+	w.lastTime = bucketStart(w.lastTime, now, w.duration, passed)
+}
+
+// Sum 计算窗口内所有值的和
+func (w *FixedWindow[B]) Sum() float64 {
+	w.rotate(time.Now())
+	return w.sum()
+}
+
+// Count 返回窗口内的非零值的数量
+func (w *FixedWindow[B]) Count() int {
+	w.rotate(time.Now())
+	return w.count()
+}
+
+// Avg 计算窗口内值的平均值
+func (w *FixedWindow[B]) Avg() float64 {
+	w.rotate(time.Now())
+	count := w.count()
+	if count == 0 {
+		return 0
+	}
+	return w.sum() / float64(count)
+}
+
+func (w *FixedWindow[B]) sum() float64 {
+	var sum float64
+	for i := 0; i < len(w.buckets); i++ {
+		sum += w.buckets[i]
+	}
+	return sum
+}
+
+func (w *FixedWindow[B]) count() int {
+	var count int
+	for i := 0; i < len(w.buckets); i++ {
+		if w.buckets[i] != 0 {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,90 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedWindow_Basic(t *testing.T) {
+	w := NewFixedWindow[[8]float64](time.Second)
+
+	w.Inc(1.0)
+	w.Inc(2.0)
+
+	if count := w.Count(); count != 1 {
+		t.Errorf("Expected count 1, got %d", count)
+	}
+
+	if sum := w.Sum(); sum != 3.0 {
+		t.Errorf("Expected sum 3.0, got %f", sum)
+	}
+
+	if avg := w.Avg(); avg != 3.0 {
+		t.Errorf("Expected average 3.0, got %f", avg)
+	}
+}
+
+func TestFixedWindow_ZeroValue(t *testing.T) {
+	var w FixedWindow[[4]float64]
+
+	w.Inc(5.0)
+	if sum := w.Sum(); sum != 5.0 {
+		t.Errorf("Expected sum 5.0, got %f", sum)
+	}
+}
+
+func TestFixedWindow_Rotation(t *testing.T) {
+	w := NewFixedWindow[[4]float64](100 * time.Millisecond)
+	w.Inc(1.0)
+
+	// Wait for the whole window to expire
+	time.Sleep(500 * time.Millisecond)
+
+	w.Inc(2.0)
+	if sum := w.Sum(); sum != 2.0 {
+		t.Errorf("Expected sum 2.0 after rotation, got %f", sum)
+	}
+}
+
+func TestFixedWindow_NegativeDuration(t *testing.T) {
+	w := NewFixedWindow[[4]float64](-time.Millisecond)
+	w.Inc(1.0)
+
+	// A non-positive duration falls back to the default instead of never rotating
+	w.rotate(w.lastTime.Add(time.Hour))
+	if sum := w.Sum(); sum != 0 {
+		t.Errorf("Expected the value to expire, got sum %f", sum)
+	}
+}
+
+func TestFixedWindow_NoAlloc(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		w := NewFixedWindow[[8]float64](time.Second)
+		w.Inc(1.0)
+		w.Sum()
+		w.Avg()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+}
+
+// Benchmarks
+
+func BenchmarkFixedWindow_PerRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := NewFixedWindow[[8]float64](time.Second)
+		w.Inc(1.0)
+		w.Avg()
+	}
+}
+
+func BenchmarkTimeWindow_PerRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := NewTimeWindow(8, time.Second)
+		w.Inc(1.0)
+		w.Avg()
+	}
+}
@@ -0,0 +1,48 @@
+package hstat
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// FrozenWindow 是窗口在某一时刻的只读快照，读取时不需要加锁，也不会随时间旋转
+// 适合在较慢的绘制过程中代替窗口本身，避免绘制期间阻塞写入
+type FrozenWindow struct {
+	view ageView
+	at   time.Time
+}
+
+// Freeze 将窗口旋转到当前时间后复制为只读快照，只在复制期间短暂加锁
+func (w *TimeWindow) Freeze() *FrozenWindow {
+	now := w.now()
+	return &FrozenWindow{
+		view: w.byAge(now),
+		at:   now,
+	}
+}
+
+// Time 返回快照的时间
+func (f *FrozenWindow) Time() time.Time {
+	return f.at
+}
+
+// ValueAt 返回指定年龄的桶的值以及该桶是否被写入过，age 超出范围时返回 0, false
+func (f *FrozenWindow) ValueAt(age int) (float64, bool) {
+	if age < 0 || age >= len(f.view.values) {
+		return 0, false
+	}
+	return f.view.values[age], f.view.written[age]
+}
+
+// PrintHistogram 绘制快照的直方图，输出与 TimeWindow.PrintHistogram 一致
+func (f *FrozenWindow) PrintHistogram(opt *HistogramOption) string {
+	var result strings.Builder
+	f.WriteHistogram(&result, opt)
+	return result.String()
+}
+
+// WriteHistogram 将快照的直方图写入 out，参见 TimeWindow.WriteHistogram
+func (f *FrozenWindow) WriteHistogram(out io.Writer, opt *HistogramOption) (int, error) {
+	return writeHistogram(out, f.view.values, f.view.duration, f.at, opt)
+}
@@ -0,0 +1,108 @@
+package hstat
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_Freeze(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(2.0)
+	clock.Add(time.Second)
+	w.Inc(5.0)
+
+	f := w.Freeze()
+	if !f.Time().Equal(clock.Now()) {
+		t.Errorf("Expected snapshot time %v, got %v", clock.Now(), f.Time())
+	}
+
+	// Later writes and rotation do not affect the snapshot
+	w.Inc(1.0)
+	clock.Add(3 * time.Second)
+	if val, ok := f.ValueAt(1); val != 2.0 || !ok {
+		t.Errorf("Expected value 2.0 at age 1, got %f (%v)", val, ok)
+	}
+	if _, ok := f.ValueAt(3); ok {
+		t.Error("Expected out of range age to report false")
+	}
+
+	if out, want := f.PrintHistogram(nil), newFilledWindow(newFakeClock(), 0, 2, 5).PrintHistogram(nil); out != want {
+		t.Errorf("Expected frozen histogram %q, got %q", want, out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramFrozen(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 3, 0, 7, 1)
+	opt := &HistogramOption{Height: 5, Width: 2}
+
+	if got, want := w.PrintHistogramFrozen(opt), w.PrintHistogram(opt); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+// benchmarkWritesDuringRender measures Inc throughput while another goroutine renders continuously
+func benchmarkWritesDuringRender(b *testing.B, render func(w *TimeWindow)) {
+	w := NewTimeWindow(600, time.Second)
+	w.ResetAll(5.0)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			render(w)
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Inc(1.0)
+	}
+	b.StopTimer()
+	stop.Store(true)
+	wg.Wait()
+}
+
+func BenchmarkTimeWindow_IncDuringPrintHistogram(b *testing.B) {
+	benchmarkWritesDuringRender(b, func(w *TimeWindow) { w.PrintHistogram(nil) })
+}
+
+func BenchmarkTimeWindow_IncDuringPrintHistogramFrozen(b *testing.B) {
+	benchmarkWritesDuringRender(b, func(w *TimeWindow) { w.PrintHistogramFrozen(nil) })
+}
+
+// benchmarkReadsDuringRender measures Sum throughput while other goroutines render continuously
+func benchmarkReadsDuringRender(b *testing.B, render func(w *TimeWindow)) {
+	w := NewTimeWindow(600, time.Second)
+	w.ResetAll(5.0)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				render(w)
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w.Sum()
+		}
+	})
+	b.StopTimer()
+	stop.Store(true)
+	wg.Wait()
+}
+
+func BenchmarkTimeWindow_SumDuringPrintHistogram(b *testing.B) {
+	benchmarkReadsDuringRender(b, func(w *TimeWindow) { w.PrintHistogram(nil) })
+}
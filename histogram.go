@@ -0,0 +1,719 @@
+package hstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Orientation 表示直方图的绘制方向
+type Orientation int
+
+const (
+	// Vertical 垂直柱状图，每个桶一列，柱向上延伸
+	Vertical Orientation = iota
+	// Horizontal 水平柱状图，每个桶一行，左侧为时间，柱向右延伸，右侧为数值
+	Horizontal
+)
+
+// 常用的 ANSI 前景色 SGR 参数，可用于 ColorThreshold 和 ColorFunc
+const (
+	ColorRed    = "31"
+	ColorGreen  = "32"
+	ColorYellow = "33"
+	ColorBlue   = "34"
+)
+
+// ColorThreshold 将大于等于 Min 的值映射为 SGR 参数，如 ColorRed 或 "1;31"
+type ColorThreshold struct {
+	Min  float64
+	Code string
+}
+
+// HistogramOption 用于配置直方图显示选项
+type HistogramOption struct {
+	Height      int         // 图表高度（如果 <= 0，则使用默认值20）
+	Width       int         // 图表宽度，垂直方向时为列数（如果 <= 0，则每个桶一列），水平方向时为柱的最大长度（如果 <= 0，则使用默认值40）
+	Orientation Orientation // 绘制方向，默认垂直
+	Signed      bool        // 是否绘制负值，为 true 时以0为基线，正值向上(水平方向时向右)、负值向下(向左)，按最大绝对值缩放
+
+	// 柱、空白和分隔线使用的字符，为0时分别使用 '▇'、' ' 和 '─'，如 ASCII 终端可以使用 '#'、' ' 和 '-'。
+	// 对齐按字符而不是字节计算，字符应为单倍宽度；设置 SeparatorRune 后 Signed 模式的零基线也使用该字符，否则为 '┈'
+	FullRune      rune
+	EmptyRune     rune
+	SeparatorRune rune
+
+	// ClipPercentile 在 (0,100) 之间时，以非零值(Signed 模式下为绝对值)的该百分位数作为满刻度而不是最大值，
+	// 超过满刻度的柱被截断为满高度，并在柱的末端用 ClipRune(为0时使用 '▲')标记
+	ClipPercentile float64
+	ClipRune       rune
+
+	// ValueFormat 为数值行中每个值的 printf 格式，为空时使用 "%.0f"，如 "%.1f" 可以显示小数；水平方向时同样用于柱末尾的数值。
+	// 两者都未设置时垂直方向每列固定占两个字符，超过两位的数值会超出所在的列；
+	// 设置任一项后每列的宽度取 2、ColumnWidth 和数值行中最宽的值三者的最大值，柱、数值和时间刻度都按该宽度对齐，
+	// 数值在列内左对齐，需要相邻的数值之间留有空格时应使 ColumnWidth 大于最宽的值
+	ValueFormat string
+	ColumnWidth int
+
+	// Overlay 大于0时在垂直柱状图上叠加移动平均线：每列取该列及之前(更早)共 Overlay 列的平均值，更早的列不足时按实际列数计算，
+	// 在平均值所在的行(即与平均值相等的柱的顶端)用 OverlayRune(为0时使用 '·')覆盖该处的柱或空白；水平方向时忽略
+	Overlay     int
+	OverlayRune rune
+
+	// LogScale 为 true 时按 log10(|v|+1) 计算柱的高度，适合跨越多个数量级的数据，数值行仍显示原值
+	LogScale bool
+
+	// 时间刻度默认为相对当前桶的秒数，如 -0.5、-1；TimeUnits 为 true 时带单位显示，如 -500ms、-1.5s、-2m；
+	// TimeFormat 非空时改为按该格式(参见 time.Layout)显示的绝对时间，优先于 TimeUnits
+	TimeUnits  bool
+	TimeFormat string
+
+	// WidthFunc 在 Width <= 0 时返回可用的输出宽度(字符数)，如当前终端的宽度，使图表随终端大小自适应：
+	// 垂直方向时桶数量超过可容纳的列数则合并相邻的桶，水平方向时据此计算柱的最大长度。
+	// 返回 <= 0 表示无法获取(如输出不是终端)，此时与未设置相同。核心包不依赖终端库，可以使用 hstatterm.WidthFunc
+	WidthFunc func() int
+
+	// YAxis 为 true 时在垂直柱状图左侧绘制纵轴，并在满刻度、一半和0所在的行标注对应的值(Signed 模式下还有对应的负值)，
+	// 启用 LogScale 时刻度为原值；水平方向时忽略
+	YAxis bool
+
+	// Color 为 true 时用 ANSI 转义序列为柱着色，输出到非终端时应保持为 false
+	Color bool
+	// ColorThresholds 按值选择颜色，取 Min 不大于该值的最后一项，应按 Min 升序排列
+	ColorThresholds []ColorThreshold
+	// ColorFunc 返回某个值对应的 SGR 参数，设置后优先于 ColorThresholds，返回空字符串表示不着色
+	ColorFunc func(value float64) string
+}
+
+// colorFor 返回值 v 对应的 SGR 参数，未启用颜色时返回空字符串
+func (opt *HistogramOption) colorFor(v float64) string {
+	if !opt.Color {
+		return ""
+	}
+	if opt.ColorFunc != nil {
+		return opt.ColorFunc(v)
+	}
+	code := ""
+	for _, t := range opt.ColorThresholds {
+		if v >= t.Min {
+			code = t.Code
+		}
+	}
+	return code
+}
+
+func (opt *HistogramOption) fullRune() rune {
+	if opt.FullRune == 0 {
+		return '▇'
+	}
+	return opt.FullRune
+}
+
+func (opt *HistogramOption) emptyRune() rune {
+	if opt.EmptyRune == 0 {
+		return ' '
+	}
+	return opt.EmptyRune
+}
+
+func (opt *HistogramOption) separatorRune() rune {
+	if opt.SeparatorRune == 0 {
+		return '─'
+	}
+	return opt.SeparatorRune
+}
+
+func (opt *HistogramOption) clipRune() rune {
+	if opt.ClipRune == 0 {
+		return '▲'
+	}
+	return opt.ClipRune
+}
+
+func (opt *HistogramOption) overlayRune() rune {
+	if opt.OverlayRune == 0 {
+		return '·'
+	}
+	return opt.OverlayRune
+}
+
+// overlayRows 返回每列移动平均值所在的行，rows 为基线以上的行数
+// 正数为基线以上的行(1为紧贴基线的行)，负数为基线以下对称的行，0表示该列不标记；未启用 Overlay 时返回 nil
+func (opt *HistogramOption) overlayRows(values []float64, scale float64, rows int) []int {
+	if opt.Overlay <= 0 {
+		return nil
+	}
+	result := make([]int, len(values))
+	for i := range values {
+		end := min(i+opt.Overlay, len(values))
+		var sum float64
+		for _, v := range values[i:end] {
+			sum += v
+		}
+		avg := sum / float64(end-i)
+		if opt.LogScale {
+			avg = math.Copysign(math.Log10(math.Abs(avg)+1), avg)
+		}
+		row := min(int(math.Abs(avg)/scale*float64(rows)), rows)
+		if avg < 0 {
+			row = -row
+		}
+		result[i] = row
+	}
+	return result
+}
+
+// axisLabels 返回纵轴每一行的刻度，下标与柱状图的行(包括零基线和底部分隔线)对应，没有刻度的行为空字符串
+// zero 为0所在的行
+func (opt *HistogramOption) axisLabels(scale float64, height int) (labels []string, zero int) {
+	if !opt.Signed {
+		labels = make([]string, height+1)
+		labels[0] = opt.axisLabel(scale)
+		if mid := height / 2; mid > 0 && mid < height {
+			labels[height-mid] = opt.axisLabel(scale * float64(mid) / float64(height))
+		}
+		labels[height] = "0"
+		return labels, height
+	}
+
+	half := max(height/2, 1)
+	labels = make([]string, 2*half+2)
+	for _, h := range []int{half, half / 2} {
+		if h == 0 {
+			continue
+		}
+		label := opt.axisLabel(scale * float64(h) / float64(half))
+		labels[half-h] = label
+		labels[half+h] = "-" + label
+	}
+	labels[half] = "0"
+	return labels, half
+}
+
+// axisLabel 将柱的高度换算为原值并保留至多两位小数
+func (opt *HistogramOption) axisLabel(b float64) string {
+	if opt.LogScale {
+		b = math.Pow(10, b) - 1
+	}
+	return strconv.FormatFloat(math.Round(b*100)/100, 'f', -1, 64)
+}
+
+// formatValue 按 ValueFormat 格式化数值
+func (opt *HistogramOption) formatValue(v float64) string {
+	format := opt.ValueFormat
+	if format == "" {
+		format = "%.0f"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+// columnWidth 返回垂直柱状图每列的字符数，未设置 ValueFormat 和 ColumnWidth 时为2
+func (opt *HistogramOption) columnWidth(values []float64) int {
+	width := max(opt.ColumnWidth, 2)
+	if opt.ValueFormat == "" && opt.ColumnWidth <= 0 {
+		return width
+	}
+	for _, v := range values {
+		if v > 0 || (opt.Signed && v != 0) {
+			width = max(width, utf8.RuneCountInString(opt.formatValue(v)))
+		}
+	}
+	return width
+}
+
+// outputWidth 返回 WidthFunc 获取的输出宽度，未设置或无法获取时返回 0
+func (opt *HistogramOption) outputWidth() int {
+	if opt.WidthFunc == nil {
+		return 0
+	}
+	return max(opt.WidthFunc(), 0)
+}
+
+// fitColumns 返回在 WidthFunc 获取的宽度内垂直柱状图最多能容纳的列数，无法获取宽度时返回 0
+// 启用纵轴时按合并前的满刻度估计纵轴的宽度，列宽也按合并前的值估计
+func (opt *HistogramOption) fitColumns(values []float64, height int) int {
+	width := opt.outputWidth()
+	if width <= 0 {
+		return 0
+	}
+	if opt.YAxis {
+		_, scale := opt.bars(values)
+		labels, _ := opt.axisLabels(scale, height)
+		width -= labelWidth(labels) + 2
+	}
+	// 默认时间刻度末尾的单位还要占一个字符
+	return max((width-1)/opt.columnWidth(values), 1)
+}
+
+// labelWidth 返回 labels 中最长的刻度的字符数
+func labelWidth(labels []string) int {
+	width := 0
+	for _, label := range labels {
+		width = max(width, utf8.RuneCountInString(label))
+	}
+	return width
+}
+
+// writeAxis 在柱状图的每一行前写入纵轴，返回纵轴的宽度，用于对齐之后的数值行和时间刻度
+func writeAxis(result histogramWriter, chart string, labels []string, zero int) int {
+	width := labelWidth(labels)
+	for i, line := range strings.SplitAfter(chart, "\n") {
+		if line == "" {
+			continue
+		}
+		axis := "│"
+		switch {
+		case i == zero:
+			axis = "┼"
+		case labels[i] != "":
+			axis = "┤"
+		}
+		fmt.Fprintf(result, "%*s %s", width, labels[i], axis)
+		result.WriteString(line)
+	}
+	return width + 2
+}
+
+// timeLabel 返回相对当前桶偏移 offset 的桶的时间刻度
+func (opt *HistogramOption) timeLabel(offset time.Duration, now time.Time) string {
+	switch {
+	case opt.TimeFormat != "":
+		return now.Add(offset).Format(opt.TimeFormat)
+	case opt.TimeUnits:
+		return formatOffset(offset)
+	default:
+		return strconv.FormatFloat(offset.Seconds(), 'f', -1, 64)
+	}
+}
+
+// formatOffset 以合适的单位格式化时间偏移，如 -500ms、-1.5s、-2m、-1h
+func formatOffset(d time.Duration) string {
+	abs := d.Abs()
+	switch {
+	case d == 0:
+		return "0"
+	case abs < time.Second:
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64) + "ms"
+	case abs < time.Minute:
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+	case abs < time.Hour:
+		return strconv.FormatFloat(d.Minutes(), 'f', -1, 64) + "m"
+	default:
+		return strconv.FormatFloat(d.Hours(), 'f', -1, 64) + "h"
+	}
+}
+
+// bars 返回决定柱高度的值及其满刻度
+// 启用 LogScale 时每个值 v 映射为 sign(v)*log10(|v|+1)，否则为原值；
+// 非有限值(±Inf、NaN)不参与满刻度的计算，绘制时按超过满刻度处理
+func (opt *HistogramOption) bars(values []float64) ([]float64, float64) {
+	bars := values
+	if opt.LogScale {
+		bars = make([]float64, len(values))
+		for i, v := range values {
+			bars[i] = math.Copysign(math.Log10(math.Abs(v)+1), v)
+		}
+	}
+
+	maxBar := 0.0
+	for _, b := range bars {
+		if isFinite(b) {
+			maxBar = max(maxBar, math.Abs(b))
+		}
+	}
+	if maxBar == 0 {
+		// 只有非有限值时使用单位刻度，使有限的0值不被绘制
+		maxBar = 1
+	}
+	return bars, opt.scale(bars, maxBar)
+}
+
+// scale 返回满刻度对应的值，未启用 ClipPercentile 时为 maxValue
+func (opt *HistogramOption) scale(values []float64, maxValue float64) float64 {
+	if opt.ClipPercentile <= 0 || opt.ClipPercentile >= 100 {
+		return maxValue
+	}
+	nonZero := make([]float64, 0, len(values))
+	for _, v := range values {
+		if v != 0 && isFinite(v) {
+			nonZero = append(nonZero, math.Abs(v))
+		}
+	}
+	sort.Float64s(nonZero)
+	if s := percentile(nonZero, opt.ClipPercentile); s > 0 {
+		return s
+	}
+	return maxValue
+}
+
+func isFinite(v float64) bool {
+	return !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
+func (opt *HistogramOption) baselineRune() rune {
+	if opt.SeparatorRune == 0 {
+		return '┈'
+	}
+	return opt.SeparatorRune
+}
+
+// writeCell 写入垂直柱状图中的一个单元格，每列占 width 个字符，柱或空白之后用空格补齐
+// clipped 为 true 时该单元格是被截断的柱的末端，marked 为 true 时该单元格为移动平均线
+func writeCell(result histogramWriter, filled, clipped, marked bool, v float64, width int, opt *HistogramOption) {
+	if clipped {
+		writeColored(result, string(opt.clipRune()), opt.colorFor(v))
+	} else if marked {
+		result.WriteRune(opt.overlayRune())
+	} else if filled {
+		writeColored(result, string(opt.fullRune()), opt.colorFor(v))
+	} else {
+		result.WriteRune(opt.emptyRune())
+	}
+	result.WriteString(strings.Repeat(" ", width-1))
+}
+
+// writeColored 写入 s，code 非空时用对应的 SGR 转义序列包裹
+func writeColored(result histogramWriter, s, code string) {
+	if code == "" {
+		result.WriteString(s)
+		return
+	}
+	result.WriteString("\x1b[" + code + "m")
+	result.WriteString(s)
+	result.WriteString("\x1b[0m")
+}
+
+// defaultHistogramHeight 是未指定 Height 时图表的默认高度
+const defaultHistogramHeight = 20
+
+// DefaultHistogramOption 返回默认的直方图配置
+func DefaultHistogramOption() *HistogramOption {
+	return &HistogramOption{
+		Height: defaultHistogramHeight,
+	}
+}
+
+// PrintHistogram 返回时间窗口内的数据分布情况，默认为垂直柱状图，可通过 Orientation 选择水平方向
+// 绘制期间一直持有窗口的读锁，不阻塞 Sum 等读取但会阻塞写入，绘制较慢时可以使用 PrintHistogramFrozen
+func (w *TimeWindow) PrintHistogram(opt *HistogramOption) string {
+	var result strings.Builder
+	w.WriteHistogram(&result, opt)
+	return result.String()
+}
+
+// WriteHistogram 将与 PrintHistogram 相同的内容直接写入 out，返回写入的字节数和遇到的第一个错误
+// 输出经过缓冲，不会在内存中拼接完整的字符串；写入期间一直持有窗口的读锁，
+// out 较慢(如网络连接)时可以先调用 Freeze，再对冻结的窗口调用 WriteHistogram
+func (w *TimeWindow) WriteHistogram(out io.Writer, opt *HistogramOption) (int, error) {
+	// 在显示之前先更新窗口状态，只有需要旋转时才短暂持有写锁
+	now := w.now()
+	w.rlockRotated(now)
+	defer w.mu.RUnlock()
+
+	// 从当前游标位置向前收集数据
+	buckets := make([]float64, w.size)
+	for i := range buckets {
+		buckets[i] = w.buckets[(w.cursor-i+w.size)%w.size]
+	}
+	return writeHistogram(out, buckets, w.duration, now, opt)
+}
+
+// PrintHistogramFrozen 与 PrintHistogram 相同，但只在复制数据时短暂加锁，绘制时不持有锁
+func (w *TimeWindow) PrintHistogramFrozen(opt *HistogramOption) string {
+	return w.Freeze().PrintHistogram(opt)
+}
+
+// histogramWriter 是绘制直方图时写入的目标，*bufio.Writer 和 *strings.Builder 都满足该接口
+type histogramWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+	WriteRune(r rune) (int, error)
+}
+
+// countingWriter 记录写入 w 的字节数
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// writeHistogram 将按年龄排列(下标0为当前桶)的桶绘制到 out，now 为当前桶对应的时间
+func writeHistogram(out io.Writer, buckets []float64, duration time.Duration, now time.Time, opt *HistogramOption) (int, error) {
+	if opt == nil {
+		opt = DefaultHistogramOption()
+	}
+
+	cw := &countingWriter{w: out}
+	result := bufio.NewWriter(cw)
+	flush := func() (int, error) {
+		err := result.Flush()
+		return cw.n, err
+	}
+
+	// 获取所有值和时间，注意顺序要从最新到最旧
+	size := len(buckets)
+	values := make([]float64, size)
+	times := make([]time.Duration, size)
+	maxValue := 0.0
+
+	for i, v := range buckets {
+		times[i] = -time.Duration(i) * duration
+
+		if opt.Signed {
+			values[i] = v
+			maxValue = max(maxValue, math.Abs(v))
+		} else if v > 0 {
+			values[i] = v
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+
+	height := opt.Height
+	if height <= 0 {
+		height = defaultHistogramHeight
+	}
+	if maxValue == 0 {
+		result.WriteString("No data available\n")
+		return flush()
+	}
+	result.WriteString("\nTime Window Histogram:\n\n")
+
+	if opt.Orientation == Horizontal {
+		bars, scale := opt.bars(values)
+		labels := make([]string, size)
+		for i, t := range times {
+			labels[i] = opt.timeLabel(t, now)
+			if opt.TimeFormat == "" && !opt.TimeUnits {
+				labels[i] += "s"
+			}
+		}
+		writeHorizontalBars(result, values, bars, labels, scale, opt)
+		return flush()
+	}
+
+	// 桶数量超过宽度时，将相邻的桶合并为一列
+	columns := opt.Width
+	if columns <= 0 {
+		columns = opt.fitColumns(values, height)
+	}
+	if columns > 0 && columns < size {
+		values, times = downsample(values, times, columns)
+	}
+	columns = len(values)
+	bars, scale := opt.bars(values)
+	colWidth := opt.columnWidth(values)
+
+	// 启用纵轴时先绘制到内存中，再逐行加上纵轴
+	var chart histogramWriter = result
+	var axisChart strings.Builder
+	if opt.YAxis {
+		chart = &axisChart
+	}
+	if opt.Signed {
+		writeSignedBars(chart, values, bars, scale, height, colWidth, opt)
+	} else {
+		overlay := opt.overlayRows(values, scale, height)
+
+		// 打印柱状图（从上到下）
+		for h := height; h > 0; h-- {
+			threshold := scale * float64(h) / float64(height)
+			for i := 0; i < columns; i++ {
+				marked := overlay != nil && overlay[i] == h
+				writeCell(chart, bars[i] >= threshold, h == height && bars[i] > scale, marked, values[i], colWidth, opt)
+			}
+			chart.WriteString("\n")
+		}
+	}
+
+	// 打印底部分隔线
+	chart.WriteString(strings.Repeat(string(opt.separatorRune()), colWidth*columns))
+	chart.WriteString("\n")
+
+	// 启用纵轴时，数值行和时间刻度向右缩进纵轴的宽度
+	indent := ""
+	if opt.YAxis {
+		labels, zero := opt.axisLabels(scale, height)
+		indent = strings.Repeat(" ", writeAxis(result, axisChart.String(), labels, zero))
+	}
+
+	// 打印数值
+	result.WriteString(indent)
+	for i := 0; i < columns; i++ {
+		if values[i] > 0 || (opt.Signed && values[i] != 0) {
+			fmt.Fprintf(result, "%-*s", colWidth, opt.formatValue(values[i]))
+		} else {
+			result.WriteString(strings.Repeat(" ", colWidth))
+		}
+	}
+	result.WriteString("\n")
+
+	// 打印相对时间刻度
+	interval := 1
+	if columns > 20 {
+		interval = columns / 10
+	}
+
+	// 打印时间刻度，每个刻度从所在列开始；与前一个刻度重叠时跳过，
+	// 两个刻度中有一个超出列宽时还要求它们之间留有空格
+	result.WriteString(indent)
+	pos, overflow := 0, false
+	for i := 0; i < columns; i += interval {
+		label := opt.timeLabel(times[i], now)
+		n := utf8.RuneCountInString(label)
+		if colWidth*i < pos || (colWidth*i == pos && pos > 0 && (overflow || n > colWidth)) {
+			continue
+		}
+		result.WriteString(strings.Repeat(" ", colWidth*i-pos))
+		result.WriteString(label)
+		pos, overflow = colWidth*i+n, n > colWidth
+	}
+	if opt.TimeFormat == "" && !opt.TimeUnits {
+		result.WriteString(strings.Repeat(" ", max(colWidth*columns-pos, 0)))
+		result.WriteString("s")
+	}
+	result.WriteString("\n")
+
+	return flush()
+}
+
+// writeSignedBars 绘制以0为基线的垂直柱状图，上下两半各占 height 的一半，每列占 width 个字符
+func writeSignedBars(result histogramWriter, values, bars []float64, maxAbs float64, height, width int, opt *HistogramOption) {
+	half := max(height/2, 1)
+	overlay := opt.overlayRows(values, maxAbs, half)
+
+	// 正值部分，从上到下
+	for h := half; h > 0; h-- {
+		threshold := maxAbs * float64(h) / float64(half)
+		for i, b := range bars {
+			marked := overlay != nil && overlay[i] == h
+			writeCell(result, b >= threshold, h == half && b > maxAbs, marked, values[i], width, opt)
+		}
+		result.WriteString("\n")
+	}
+
+	// 零基线
+	result.WriteString(strings.Repeat(string(opt.baselineRune()), width*len(values)))
+	result.WriteString("\n")
+
+	// 负值部分，从上到下
+	for h := 1; h <= half; h++ {
+		threshold := maxAbs * float64(h) / float64(half)
+		for i, b := range bars {
+			marked := overlay != nil && overlay[i] == -h
+			writeCell(result, -b >= threshold, h == half && -b > maxAbs, marked, values[i], width, opt)
+		}
+		result.WriteString("\n")
+	}
+}
+
+// downsample 将按从新到旧排列的桶合并为 columns 列
+// 每列取其覆盖的所有桶(包括空桶)的平均值，时间取该列中最新的桶的时间
+func downsample(values []float64, times []time.Duration, columns int) ([]float64, []time.Duration) {
+	size := len(values)
+	outValues := make([]float64, columns)
+	outTimes := make([]time.Duration, columns)
+	for c := 0; c < columns; c++ {
+		start := c * size / columns
+		end := (c + 1) * size / columns
+
+		var sum float64
+		for i := start; i < end; i++ {
+			sum += values[i]
+		}
+		outValues[c] = sum / float64(end-start)
+		outTimes[c] = times[start]
+	}
+	return outValues, outTimes
+}
+
+// writeHorizontalBars 绘制水平柱状图，每个桶一行，按最大值等比缩放柱的长度
+func writeHorizontalBars(result histogramWriter, values, bars []float64, labels []string, maxValue float64, opt *HistogramOption) {
+	empty := string(opt.emptyRune())
+
+	labelCols := labelWidth(labels)
+
+	width := opt.Width
+	if width <= 0 {
+		width = opt.fitBarWidth(values, labelCols)
+	}
+	if width <= 0 {
+		width = 40
+	}
+
+	if opt.Signed {
+		// 以0为中轴，负值向左、正值向右，左右各占 width
+		for i, v := range values {
+			bar, n := horizontalBar(bars[i], maxValue, width, opt)
+			fmt.Fprintf(result, "%*s ", labelCols, labels[i])
+			if v < 0 {
+				result.WriteString(strings.Repeat(empty, width-n))
+				writeColored(result, bar, opt.colorFor(v))
+				result.WriteString("│")
+			} else {
+				result.WriteString(strings.Repeat(empty, width))
+				result.WriteString("│")
+				writeColored(result, bar, opt.colorFor(v))
+			}
+			if v != 0 {
+				result.WriteString(" " + opt.formatValue(v))
+			}
+			result.WriteString("\n")
+		}
+		return
+	}
+
+	for i, v := range values {
+		fmt.Fprintf(result, "%*s │", labelCols, labels[i])
+		if v > 0 {
+			bar, _ := horizontalBar(bars[i], maxValue, width, opt)
+			writeColored(result, bar, opt.colorFor(v))
+			result.WriteString(" " + opt.formatValue(v))
+		}
+		result.WriteString("\n")
+	}
+}
+
+// fitBarWidth 返回在 WidthFunc 获取的宽度内水平柱的最大长度，无法获取宽度时返回 0
+// 每行由时间刻度、分隔符、柱和末尾的数值组成，Signed 模式下左右两侧各占一个柱长
+func (opt *HistogramOption) fitBarWidth(values []float64, labelWidth int) int {
+	width := opt.outputWidth()
+	if width <= 0 {
+		return 0
+	}
+	valueWidth := 0
+	for _, v := range values {
+		valueWidth = max(valueWidth, utf8.RuneCountInString(opt.formatValue(v))+1)
+	}
+	width -= labelWidth + 2 + valueWidth
+	if opt.Signed {
+		width /= 2
+	}
+	return max(width, 1)
+}
+
+// horizontalBar 返回按 scale 缩放后长度为 n 的水平柱，超过满刻度的柱截断为 width 并在远离中轴的一端标记
+func horizontalBar(v, scale float64, width int, opt *HistogramOption) (string, int) {
+	full := string(opt.fullRune())
+	if math.Abs(v) <= scale {
+		n := int(math.Round(math.Abs(v) / scale * float64(width)))
+		return strings.Repeat(full, n), n
+	}
+	if v < 0 {
+		return string(opt.clipRune()) + strings.Repeat(full, width-1), width
+	}
+	return strings.Repeat(full, width-1) + string(opt.clipRune()), width
+}
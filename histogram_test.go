@@ -0,0 +1,591 @@
+package hstat
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTimeWindow_PrintHistogramHorizontal(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 5, 0, 10)
+
+	out := w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 10})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	rows := lines[len(lines)-3:]
+
+	// Rows run newest first: 0s, -1s, -2s
+	want := []string{
+		" 0s │▇▇▇▇▇▇▇▇▇▇ 10",
+		"-1s │",
+		"-2s │▇▇▇▇▇ 5",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramNonFinite(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 5, math.Inf(1), 10)
+
+	// The infinite bucket is left out of the scale and drawn clipped
+	out := w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 10})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	rows := lines[len(lines)-3:]
+	want := []string{
+		" 0s │▇▇▇▇▇▇▇▇▇▇ 10",
+		"-1s │▇▇▇▇▇▇▇▇▇▲ +Inf",
+		"-2s │▇▇▇▇▇ 5",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+
+	// Signed and vertical modes must not panic either, even without any finite data
+	for _, w := range []*TimeWindow{w, newFilledWindow(newFakeClock(), math.Inf(-1), math.NaN())} {
+		for _, opt := range []*HistogramOption{
+			{Orientation: Horizontal, Signed: true, Width: 10},
+			{Height: 4},
+			{Height: 4, Signed: true},
+		} {
+			w.PrintHistogram(opt)
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramHorizontalWideLabels(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 5, 0, 10)
+
+	// Label width is counted in characters, not bytes
+	out := w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 10, TimeFormat: "05秒"})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	rows := lines[len(lines)-3:]
+
+	want := []string{
+		"02秒 │▇▇▇▇▇▇▇▇▇▇ 10",
+		"01秒 │",
+		"00秒 │▇▇▇▇▇ 5",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramHorizontalDefaultWidth(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(1.0)
+
+	out := w.PrintHistogram(&HistogramOption{Orientation: Horizontal})
+	if n := strings.Count(out, "▇"); n != 40 {
+		t.Errorf("Expected a bar of 40 cells, got %d", n)
+	}
+}
+
+func TestTimeWindow_PrintHistogramWidth(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 3, 2, 6, 7, 9)
+	opt := &HistogramOption{Height: 4, Width: 3}
+
+	out := w.PrintHistogram(opt)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+
+	// Six buckets are averaged pairwise, newest first: 8, 4, 2
+	if values := lines[len(lines)-2]; values != "8 4 2 " {
+		t.Errorf("Expected values row %q, got %q", "8 4 2 ", values)
+	}
+	if ticks := lines[len(lines)-1]; ticks != "0 -2-4s" {
+		t.Errorf("Expected time row %q, got %q", "0 -2-4s", ticks)
+	}
+	if sep := lines[len(lines)-3]; sep != strings.Repeat("──", 3) {
+		t.Errorf("Expected separator of 3 columns, got %q", sep)
+	}
+}
+
+func TestTimeWindow_PrintHistogramWidthLargerThanSize(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2, 4, 6)
+
+	wide := w.PrintHistogram(&HistogramOption{Height: 4, Width: 10})
+	plain := w.PrintHistogram(&HistogramOption{Height: 4})
+	if wide != plain {
+		t.Errorf("Expected Width >= size to match default output, got:\n%s\nwant:\n%s", wide, plain)
+	}
+}
+
+func TestTimeWindow_PrintHistogramColor(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 4, 9)
+	thresholds := []ColorThreshold{{Min: 0, Code: ColorGreen}, {Min: 5, Code: ColorRed}}
+
+	plain := w.PrintHistogram(&HistogramOption{Height: 3, ColorThresholds: thresholds})
+	if strings.Contains(plain, "\x1b[") {
+		t.Error("Expected no escape codes when Color is false")
+	}
+
+	colored := w.PrintHistogram(&HistogramOption{Height: 3, Color: true, ColorThresholds: thresholds})
+	if !strings.Contains(colored, "\x1b[31m▇\x1b[0m") || !strings.Contains(colored, "\x1b[32m▇\x1b[0m") {
+		t.Errorf("Expected red and green bars, got %q", colored)
+	}
+
+	// Stripping the escape codes gives back the plain output, including the value and time rows
+	stripped := strings.NewReplacer("\x1b[31m", "", "\x1b[32m", "", "\x1b[0m", "").Replace(colored)
+	if stripped != plain {
+		t.Errorf("Expected %q after stripping colors, got %q", plain, stripped)
+	}
+}
+
+func TestTimeWindow_PrintHistogramColorFunc(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2, 9)
+	opt := &HistogramOption{
+		Orientation: Horizontal,
+		Width:       4,
+		Color:       true,
+		ColorFunc: func(v float64) string {
+			if v > 5 {
+				return ColorYellow
+			}
+			return ""
+		},
+	}
+
+	out := w.PrintHistogram(opt)
+	if !strings.Contains(out, "│\x1b[33m▇▇▇▇\x1b[0m 9\n") {
+		t.Errorf("Expected a yellow bar for 9, got %q", out)
+	}
+	if !strings.Contains(out, "│▇ 2\n") {
+		t.Errorf("Expected a plain bar for 2, got %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramSigned(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(4.0)
+	clock.Add(time.Second)
+	w.Dec(2.0)
+	clock.Add(time.Second)
+	w.Dec(4.0)
+
+	out := w.PrintHistogram(&HistogramOption{Height: 4, Signed: true})
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	rows := lines[len(lines)-8:]
+
+	// Columns run newest first: -4, -2, 4
+	want := []string{
+		"    ▇ ",
+		"    ▇ ",
+		"┈┈┈┈┈┈",
+		"▇ ▇   ",
+		"▇     ",
+		"──────",
+		"-4-24 ",
+		"0 -1-2s",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramSignedHorizontal(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(2, time.Second, WithClock(clock.Now))
+	w.Inc(4.0)
+	clock.Add(time.Second)
+	w.Dec(2.0)
+
+	out := w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 4, Signed: true})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	rows := lines[len(lines)-2:]
+
+	want := []string{
+		" 0s   ▇▇│ -2",
+		"-1s     │▇▇▇▇ 4",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramUnsignedHidesNegative(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Dec(2.0)
+
+	if out := w.PrintHistogram(nil); out != "No data available\n" {
+		t.Errorf("Expected no data without Signed, got %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramRunes(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2, 4)
+	opt := &HistogramOption{Height: 2, FullRune: '#', EmptyRune: '.', SeparatorRune: '-'}
+
+	out := w.PrintHistogram(opt)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	rows := lines[len(lines)-5:]
+
+	want := []string{
+		"# . ",
+		"# # ",
+		"----",
+		"4 2 ",
+		"0 -1s",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+
+	// The default glyphs are unchanged
+	def := w.PrintHistogram(&HistogramOption{Height: 2})
+	if !strings.Contains(def, "▇ ▇ \n────\n") {
+		t.Errorf("Expected default glyphs, got %q", def)
+	}
+
+	opt.Orientation = Horizontal
+	opt.Width = 4
+	if out := w.PrintHistogram(opt); !strings.Contains(out, " 0s │#### 4\n-1s │## 2\n") {
+		t.Errorf("Expected ASCII horizontal bars, got %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramClip(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2, 4, 100, 4)
+	opt := &HistogramOption{Height: 2, ClipPercentile: 50}
+
+	// The median of 2, 4, 4, 100 is 4, so the spike is clipped to the top row
+	out := w.PrintHistogram(opt)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	rows := lines[len(lines)-5 : len(lines)-3]
+	want := []string{
+		"▇ ▲ ▇   ",
+		"▇ ▇ ▇ ▇ ",
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+
+	// Without clipping the spike dominates and the small values vanish
+	out = w.PrintHistogram(&HistogramOption{Height: 2})
+	lines = strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if top := lines[len(lines)-5]; top != "  ▇     " {
+		t.Errorf("Expected only the spike in the top row, got %q", top)
+	}
+
+	opt.Orientation = Horizontal
+	opt.Width = 4
+	out = w.PrintHistogram(opt)
+	if !strings.Contains(out, "-1s │▇▇▇▲ 100\n") || !strings.Contains(out, " 0s │▇▇▇▇ 4\n") {
+		t.Errorf("Expected a clipped horizontal bar, got %q", out)
+	}
+}
+
+// barHeights counts the filled cells of each column in the top rows of a vertical histogram
+func barHeights(out string, height, columns int) []int {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	rows := lines[len(lines)-3-height : len(lines)-3]
+
+	heights := make([]int, columns)
+	for _, row := range rows {
+		cells := []rune(row)
+		for c := 0; c < columns; c++ {
+			if cells[2*c] == '▇' {
+				heights[c]++
+			}
+		}
+	}
+	return heights
+}
+
+func TestTimeWindow_PrintHistogramLogScale(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 10, 100, 9999)
+
+	// Columns run newest first: 9999, 100, 10, 1
+	linear := barHeights(w.PrintHistogram(&HistogramOption{Height: 4}), 4, 4)
+	for i, want := range []int{4, 0, 0, 0} {
+		if linear[i] != want {
+			t.Errorf("Linear column %d: expected height %d, got %d", i, want, linear[i])
+		}
+	}
+
+	out := w.PrintHistogram(&HistogramOption{Height: 4, LogScale: true})
+	logged := barHeights(out, 4, 4)
+	for i, want := range []int{4, 2, 1, 0} {
+		if logged[i] != want {
+			t.Errorf("Log column %d: expected height %d, got %d", i, want, logged[i])
+		}
+	}
+
+	// The value row still shows the real values
+	if !strings.Contains(out, "9999100") {
+		t.Errorf("Expected real values in the value row, got %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramTimeLabels(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(6, 500*time.Millisecond, WithClock(clock.Now))
+	for i := 0; i < 6; i++ {
+		w.Inc(float64(i + 1))
+		clock.Add(500 * time.Millisecond)
+	}
+	w.Inc(7)
+
+	lastLine := func(out string) string {
+		lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+		return lines[len(lines)-1]
+	}
+
+	// Sub-second durations no longer truncate every label to 0
+	if got, want := lastLine(w.PrintHistogram(&HistogramOption{Height: 2})), "0 -0.5  -2  s"; got != want {
+		t.Errorf("Expected time row %q, got %q", want, got)
+	}
+
+	if got, want := lastLine(w.PrintHistogram(&HistogramOption{Height: 2, TimeUnits: true})), "0 -500ms  -2.5s"; got != want {
+		t.Errorf("Expected time row %q, got %q", want, got)
+	}
+
+	out := w.PrintHistogram(&HistogramOption{Height: 2, TimeFormat: "05.0"})
+	if got, want := lastLine(out), "03.0  01.5"; got != want {
+		t.Errorf("Expected time row %q, got %q", want, got)
+	}
+
+	out = w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 4, TimeUnits: true})
+	for _, label := range []string{"     0 │", "-500ms │", "   -1s │", " -2.5s │"} {
+		if !strings.Contains(out, "\n"+label) {
+			t.Errorf("Expected row label %q, got %q", label, out)
+		}
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                        "0",
+		-250 * time.Millisecond:  "-250ms",
+		-1500 * time.Millisecond: "-1.5s",
+		-90 * time.Second:        "-1.5m",
+		-2 * time.Hour:           "-2h",
+	}
+	for d, want := range cases {
+		if got := formatOffset(d); got != want {
+			t.Errorf("formatOffset(%s): expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramYAxis(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2500, 10000)
+
+	// Without the option the output is unchanged
+	plain := w.PrintHistogram(&HistogramOption{Height: 4})
+	if strings.Contains(plain, "┤") {
+		t.Errorf("Expected no axis by default, got %q", plain)
+	}
+
+	out := w.PrintHistogram(&HistogramOption{Height: 4, YAxis: true})
+	want := "\nTime Window Histogram:\n\n" +
+		"10000 ┤▇   \n" +
+		"      │▇   \n" +
+		" 5000 ┤▇   \n" +
+		"      │▇ ▇ \n" +
+		"    0 ┼────\n" +
+		"       100002500\n" +
+		"       0 -1s\n"
+	if out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	// Signed mode labels the negative half too
+	w = newFilledWindow(newFakeClock(), -4, 4)
+	out = w.PrintHistogram(&HistogramOption{Height: 4, Signed: true, YAxis: true})
+	for _, row := range []string{"\n 4 ┤▇", "\n 2 ┤▇", "\n 0 ┼┈", "\n-2 ┤", "\n-4 ┤"} {
+		if !strings.Contains(out, row) {
+			t.Errorf("Expected row starting %q, got %q", row, out)
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramInvalidHeight(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 2, 4)
+	want := w.PrintHistogram(nil)
+
+	for _, height := range []int{0, -5} {
+		out := w.PrintHistogram(&HistogramOption{Height: height})
+		if out != want {
+			t.Errorf("Height %d: expected the default chart %q, got %q", height, want, out)
+		}
+		if strings.Contains(out, "NaN") {
+			t.Errorf("Height %d: unexpected NaN in %q", height, out)
+		}
+	}
+}
+
+// failingWriter rejects every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestTimeWindow_WriteHistogram(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 3, -1, 0, 7, 2)
+
+	opts := []*HistogramOption{
+		nil,
+		{Height: 4, YAxis: true},
+		{Height: 4, Signed: true, Color: true, ColorThresholds: []ColorThreshold{{Min: 5, Code: ColorRed}}},
+		{Orientation: Horizontal, Width: 10},
+	}
+	for i, opt := range opts {
+		var buf bytes.Buffer
+		n, err := w.WriteHistogram(&buf, opt)
+		if err != nil {
+			t.Fatalf("Option %d: unexpected error: %v", i, err)
+		}
+		if want := w.PrintHistogram(opt); buf.String() != want {
+			t.Errorf("Option %d: expected %q, got %q", i, want, buf.String())
+		}
+		if n != buf.Len() {
+			t.Errorf("Option %d: expected %d bytes written, got %d", i, buf.Len(), n)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := NewTimeWindow(3, time.Second).WriteHistogram(&buf, nil); err != nil || buf.String() != "No data available\n" {
+		t.Errorf("Expected the empty message, got %q (%v)", buf.String(), err)
+	}
+
+	if _, err := w.WriteHistogram(failingWriter{}, nil); err == nil {
+		t.Error("Expected the writer error to be returned")
+	}
+}
+
+func TestTimeWindow_PrintHistogramReadLock(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+
+	// Rendering without a pending rotation only needs the read lock
+	w.mu.RLock()
+	done := make(chan string)
+	go func() { done <- w.PrintHistogram(nil) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected PrintHistogram to proceed alongside another reader")
+	}
+	w.mu.RUnlock()
+
+	// A pending rotation is still applied before rendering
+	clock.Add(5 * time.Second)
+	if out := w.PrintHistogram(nil); out != "No data available\n" {
+		t.Errorf("Expected expired data to be rotated out, got %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramWidthFunc(t *testing.T) {
+	w := NewTimeWindow(40, time.Second, WithClock(newFakeClock().Now))
+	w.ResetAll(5)
+
+	// 21 characters fit 10 columns plus the trailing unit; the title lines are not resized
+	out := w.PrintHistogram(&HistogramOption{Height: 2, WidthFunc: func() int { return 21 }})
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n")[3:] {
+		if n := utf8.RuneCountInString(line); n > 21 {
+			t.Errorf("Expected lines within 21 characters, got %d in %q", n, line)
+		}
+	}
+	if want := strings.Repeat("─", 20); !strings.Contains(out, "\n"+want+"\n") {
+		t.Errorf("Expected 10 columns, got %q", out)
+	}
+
+	// An explicit Width wins, and a failed detection falls back to one column per bucket
+	if got, want := w.PrintHistogram(&HistogramOption{Height: 2, Width: 8, WidthFunc: func() int { return 21 }}),
+		w.PrintHistogram(&HistogramOption{Height: 2, Width: 8}); got != want {
+		t.Errorf("Expected Width to take precedence, got %q", got)
+	}
+	if got, want := w.PrintHistogram(&HistogramOption{Height: 2, WidthFunc: func() int { return 0 }}),
+		w.PrintHistogram(&HistogramOption{Height: 2}); got != want {
+		t.Errorf("Expected the default layout when detection fails, got %q", got)
+	}
+
+	// Horizontal bars shrink to fit the line
+	out = w.PrintHistogram(&HistogramOption{Orientation: Horizontal, WidthFunc: func() int { return 30 }})
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n")[3:] {
+		if n := utf8.RuneCountInString(line); n > 30 {
+			t.Errorf("Expected lines within 30 characters, got %d in %q", n, line)
+		}
+	}
+}
+
+func TestTimeWindow_PrintHistogramValueFormat(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 0.5, 12, 300, 1500)
+
+	out := w.PrintHistogram(&HistogramOption{Height: 2, ValueFormat: "%.1f", ColumnWidth: 7})
+	want := "\nTime Window Histogram:\n\n" +
+		"▇" + strings.Repeat(" ", 27) + "\n" +
+		"▇" + strings.Repeat(" ", 27) + "\n" +
+		strings.Repeat("─", 28) + "\n" +
+		"1500.0 300.0  12.0   0.5    \n" +
+		"0      -1     -2     -3     s\n"
+	if out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	// Columns grow to fit the widest value when ColumnWidth is smaller
+	out = w.PrintHistogram(&HistogramOption{Height: 2, ValueFormat: "%6.1f"})
+	lines := strings.Split(out, "\n")
+	if sep := lines[5]; utf8.RuneCountInString(sep) != 4*6 {
+		t.Errorf("Expected separator of 24 characters, got %q", sep)
+	}
+	if row := lines[6]; row != "1500.0 300.0  12.0   0.5" {
+		t.Errorf("Unexpected value row %q", row)
+	}
+
+	// Horizontal bars use the same format
+	out = w.PrintHistogram(&HistogramOption{Orientation: Horizontal, Width: 4, ValueFormat: "%.1f"})
+	if !strings.Contains(out, "▇▇▇▇ 1500.0\n") || !strings.Contains(out, " 0.5\n") {
+		t.Errorf("Expected formatted values in %q", out)
+	}
+}
+
+func TestTimeWindow_PrintHistogramOverlay(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 4, 0, 4, 0)
+
+	// Without the option the output is unchanged
+	if out := w.PrintHistogram(&HistogramOption{Height: 4}); strings.Contains(out, "·") {
+		t.Errorf("Expected no overlay by default, got %q", out)
+	}
+
+	// Trailing averages of two columns are 2, 2, 2 and 4 for the oldest column
+	lines := strings.Split(w.PrintHistogram(&HistogramOption{Height: 4, Overlay: 2}), "\n")
+	want := []string{
+		"  ▇   · ",
+		"  ▇   ▇ ",
+		"· · · ▇ ",
+		"  ▇   ▇ ",
+	}
+	for i, row := range want {
+		if lines[3+i] != row {
+			t.Errorf("Row %d: expected %q, got %q", i, row, lines[3+i])
+		}
+	}
+
+	// Negative averages are marked below the baseline
+	w = newFilledWindow(newFakeClock(), -4, -4)
+	out := w.PrintHistogram(&HistogramOption{Height: 4, Signed: true, Overlay: 2, OverlayRune: '*'})
+	if lines := strings.Split(out, "\n"); lines[7] != "* * " {
+		t.Errorf("Expected overlay in the bottom row, got %q", out)
+	}
+}
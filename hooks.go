@@ -0,0 +1,63 @@
+package hstat
+
+// OnRotate 注册窗口旋转时的回调，参数为本次旋转清空的桶数量(整体清空时为窗口大小)
+// 回调在释放锁之后同步执行，因此可以在回调中再次访问窗口；再次注册会替换之前的回调，传入 nil 取消注册
+func (w *TimeWindow) OnRotate(fn func(bucketsRotated int)) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.onRotate = fn
+}
+
+// OnUpdate 注册数据更新时的回调，参数为更新后当前桶的值
+// Inc、Dec、Append、Reset、Add 及其 *At 变体和 Merge 会触发该回调；
+// 回调在释放锁之后同步执行，再次注册会替换之前的回调，传入 nil 取消注册
+func (w *TimeWindow) OnUpdate(fn func(value float64)) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.onUpdate = fn
+}
+
+// markUpdated 记录当前桶被更新，调用方需持有写锁
+func (w *TimeWindow) markUpdated() {
+	w.updated = true
+	w.updatedValue = w.buckets[w.cursor]
+	if w.checkThreshold() {
+		w.alert = true
+	}
+}
+
+// unlock 释放写锁，然后在锁外同步执行持锁期间挂起的回调
+func (w *TimeWindow) unlock() {
+	onRotate, rotated := w.onRotate, w.rotated
+	onUpdate, updated, value := w.onUpdate, w.updated, w.updatedValue
+	var alertCh chan<- float64
+	if w.alert && w.threshold != nil {
+		alertCh = w.threshold.ch
+	}
+	sum := w.sum
+	var sumChanged chan struct{}
+	if w.sumChanged != nil && sum != w.sumSeen {
+		sumChanged, w.sumChanged = w.sumChanged, nil
+	}
+	w.rotated, w.updated, w.alert = 0, false, false
+	w.mu.Unlock()
+
+	if sumChanged != nil {
+		close(sumChanged)
+	}
+
+	if alertCh != nil {
+		select {
+		case alertCh <- sum:
+		default:
+		}
+	}
+	if onRotate != nil && rotated > 0 {
+		onRotate(rotated)
+	}
+	if onUpdate != nil && updated {
+		onUpdate(value)
+	}
+}
@@ -0,0 +1,80 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_OnRotate(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	var rotations []int
+	w.OnRotate(func(n int) {
+		rotations = append(rotations, n)
+	})
+
+	w.Inc(1)
+	clock.Add(2 * time.Second)
+	w.Inc(1)
+	clock.Add(time.Minute)
+	w.Inc(1)
+
+	if len(rotations) != 2 || rotations[0] != 2 || rotations[1] != 5 {
+		t.Errorf("Expected rotations [2 5], got %v", rotations)
+	}
+}
+
+func TestTimeWindow_OnUpdate(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+
+	var values []float64
+	w.OnUpdate(func(v float64) {
+		values = append(values, v)
+	})
+
+	w.Inc(3)
+	w.Dec(1)
+	w.Append(7)
+	w.Reset(4)
+	w.Add(2)
+
+	want := []float64{3, 2, 7, 4, 6}
+	if len(values) != len(want) {
+		t.Fatalf("Expected %d updates, got %v", len(want), values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("Update %d: expected %f, got %f", i, want[i], values[i])
+		}
+	}
+
+	// Read-only calls do not fire the update hook
+	w.Sum()
+	w.GetData()
+	if len(values) != len(want) {
+		t.Errorf("Expected no update from reads, got %v", values)
+	}
+}
+
+func TestTimeWindow_HooksRunOutsideLock(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	// Calling back into the window would deadlock if the lock were still held
+	var sum float64
+	w.OnUpdate(func(float64) {
+		sum = w.Sum()
+	})
+	w.OnRotate(func(int) {
+		w.GetData()
+	})
+
+	w.Inc(2)
+	clock.Add(time.Second)
+	w.Inc(3)
+
+	if sum != 5 {
+		t.Errorf("Expected sum 5 seen from the hook, got %f", sum)
+	}
+}
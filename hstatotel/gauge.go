@@ -0,0 +1,43 @@
+// Package hstatotel 将 hstat 时间窗口导出为 OpenTelemetry 指标
+// 单独作为子包，使 hstat 核心包不依赖 OpenTelemetry
+package hstatotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"pkg.blksails.net/x/hstat"
+)
+
+// Register 为窗口注册异步仪表盘，每个采集周期通过 AggregateSnapshot 旋转窗口后读取 Sum、Avg、Count，
+// 空闲窗口中过期的数据不会被继续导出
+// prefix: 指标名前缀，指标名为 <prefix>.sum、<prefix>.avg 和 <prefix>.count
+// attrs: 附加在每个观测值上的属性，用同一前缀注册多个窗口时用于区分窗口
+// 不再需要导出时调用返回值的 Unregister
+func Register(meter metric.Meter, prefix string, w *hstat.TimeWindow, attrs ...attribute.KeyValue) (metric.Registration, error) {
+	sum, err := meter.Float64ObservableGauge(prefix+".sum",
+		metric.WithDescription("Sum of all bucket values in the time window."))
+	if err != nil {
+		return nil, err
+	}
+	avg, err := meter.Float64ObservableGauge(prefix+".avg",
+		metric.WithDescription("Average of the populated buckets in the time window."))
+	if err != nil {
+		return nil, err
+	}
+	count, err := meter.Int64ObservableGauge(prefix+".count",
+		metric.WithDescription("Number of populated buckets in the time window."))
+	if err != nil {
+		return nil, err
+	}
+
+	opt := metric.WithAttributes(attrs...)
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := w.AggregateSnapshot()
+		o.ObserveFloat64(sum, s.Sum, opt)
+		o.ObserveFloat64(avg, s.Avg, opt)
+		o.ObserveInt64(count, int64(s.Count), opt)
+		return nil
+	}, sum, avg, count)
+}
@@ -0,0 +1,101 @@
+package hstatotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"pkg.blksails.net/x/hstat"
+)
+
+// collect reads all gauges from the reader, keyed by metric name
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]float64 {
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[float64]:
+				for _, dp := range data.DataPoints {
+					if v, ok := dp.Attributes.Value("window"); !ok || v.AsString() != "online" {
+						t.Errorf("Unexpected attributes %v on %s", dp.Attributes, m.Name)
+					}
+					got[m.Name] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] = float64(dp.Value)
+				}
+			}
+		}
+	}
+	return got
+}
+
+func TestRegister(t *testing.T) {
+	w := hstat.NewTimeWindow(5, time.Second)
+	w.Inc(3)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	reg, err := Register(meter, "app.window", w, attribute.String("window", "online"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]float64{
+		"app.window.sum":   3,
+		"app.window.avg":   3,
+		"app.window.count": 1,
+	}
+	got := collect(t, reader)
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("Expected %s = %f, got %f", name, v, got[name])
+		}
+	}
+
+	// Each collection reads the current window state
+	w.Inc(2)
+	if got := collect(t, reader); got["app.window.sum"] != 5 {
+		t.Errorf("Expected updated sum 5, got %f", got["app.window.sum"])
+	}
+
+	if err := reg.Unregister(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := collect(t, reader); len(got) != 0 {
+		t.Errorf("Expected no observations after Unregister, got %v", got)
+	}
+}
+
+func TestRegisterIdleWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := hstat.NewTimeWindow(3, time.Second, hstat.WithClock(func() time.Time { return now }))
+	w.Inc(7)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if _, err := Register(meter, "app.window", w, attribute.String("window", "online")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := collect(t, reader); got["app.window.sum"] != 7 {
+		t.Errorf("Expected sum 7, got %f", got["app.window.sum"])
+	}
+
+	// Nothing is written while the window goes idle; the data must expire
+	now = now.Add(10 * time.Second)
+	got := collect(t, reader)
+	if got["app.window.sum"] != 0 || got["app.window.count"] != 0 {
+		t.Errorf("Expected an expired window to export zeros, got %v", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Package hstatpb 提供 hstat 时间窗口的 Protocol Buffers 表示，用于与其他语言的服务交换窗口快照
+// 单独作为子包，使 hstat 核心包不依赖 protobuf；消息定义见 hstat.proto
+package hstatpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative hstat.proto
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"pkg.blksails.net/x/hstat"
+)
+
+// ToProto 将窗口的当前状态转换为 protobuf 消息，内容与 TimeWindow.Raw 一致
+// 零值时间对应未设置的字段
+func ToProto(w *hstat.TimeWindow) *TimeWindow {
+	header, buckets := w.Raw()
+	return &TimeWindow{
+		Size:       int32(header.Size),
+		Duration:   durationpb.New(header.Duration),
+		Cursor:     int32(header.Cursor),
+		LastTime:   timestampOrNil(header.LastTime),
+		LastUpdate: timestampOrNil(header.LastUpdate),
+		Buckets:    buckets,
+	}
+}
+
+// FromProto 由 ToProto 生成的消息还原窗口，校验规则与 hstat.NewTimeWindowFromRaw 相同
+// 与 Raw 一样不包含写入标记，还原后按非零值推断桶是否被写入过
+func FromProto(m *TimeWindow, opts ...hstat.Option) (*hstat.TimeWindow, error) {
+	header := hstat.WindowHeader{
+		Size:       int(m.GetSize()),
+		Duration:   m.GetDuration().AsDuration(),
+		Cursor:     int(m.GetCursor()),
+		LastTime:   timeOrZero(m.GetLastTime()),
+		LastUpdate: timeOrZero(m.GetLastUpdate()),
+	}
+	return hstat.NewTimeWindowFromRaw(header, m.GetBuckets(), opts...)
+}
+
+func timestampOrNil(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeOrZero(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,76 @@
+package hstatpb
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"pkg.blksails.net/x/hstat"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	w := hstat.NewTimeWindow(4, time.Second, hstat.WithClock(clock))
+
+	// Wrap the cursor so storage order differs from age order
+	for i := 1; i <= 6; i++ {
+		w.Inc(float64(i))
+		now = now.Add(time.Second)
+	}
+	now = now.Add(-time.Second)
+
+	data, err := proto.Marshal(ToProto(w))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var m TimeWindow
+	if err := proto.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, err := FromProto(&m, hstat.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantHeader, wantBuckets := w.Raw()
+	gotHeader, gotBuckets := got.Raw()
+	if gotHeader.Size != wantHeader.Size || gotHeader.Duration != wantHeader.Duration || gotHeader.Cursor != wantHeader.Cursor {
+		t.Errorf("Expected header %+v, got %+v", wantHeader, gotHeader)
+	}
+	if !gotHeader.LastTime.Equal(wantHeader.LastTime) || !gotHeader.LastUpdate.Equal(wantHeader.LastUpdate) {
+		t.Errorf("Expected times %v/%v, got %v/%v",
+			wantHeader.LastTime, wantHeader.LastUpdate, gotHeader.LastTime, gotHeader.LastUpdate)
+	}
+	for i := range wantBuckets {
+		if gotBuckets[i] != wantBuckets[i] {
+			t.Errorf("Bucket %d: expected %f, got %f", i, wantBuckets[i], gotBuckets[i])
+		}
+	}
+	if got.Sum() != w.Sum() {
+		t.Errorf("Expected sum %f, got %f", w.Sum(), got.Sum())
+	}
+}
+
+func TestProtoZeroTimes(t *testing.T) {
+	w := hstat.NewTimeWindow(3, time.Second)
+	m := ToProto(w)
+	if m.GetLastUpdate() != nil {
+		t.Errorf("Expected unset last update for a never updated window, got %v", m.GetLastUpdate())
+	}
+
+	got, err := FromProto(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !got.LastUpdateTime().IsZero() {
+		t.Errorf("Expected zero last update, got %v", got.LastUpdateTime())
+	}
+}
+
+func TestFromProtoInvalid(t *testing.T) {
+	m := &TimeWindow{Size: 3, Buckets: []float64{1, 2}}
+	if _, err := FromProto(m); err == nil {
+		t.Error("Expected error for a bucket count mismatch")
+	}
+}
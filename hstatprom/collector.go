@@ -0,0 +1,79 @@
+// Package hstatprom 将 hstat 时间窗口导出为 Prometheus 指标
+// 单独作为子包，使 hstat 核心包不依赖 Prometheus 客户端
+package hstatprom
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"pkg.blksails.net/x/hstat"
+)
+
+// Collector 实现 prometheus.Collector 接口，将一组命名的时间窗口的
+// Sum、Avg、Count、Min、Max 导出为仪表盘指标，窗口名作为 window 标签
+type Collector struct {
+	mu      sync.RWMutex
+	windows map[string]*hstat.TimeWindow
+
+	sum   *prometheus.Desc
+	avg   *prometheus.Desc
+	count *prometheus.Desc
+	min   *prometheus.Desc
+	max   *prometheus.Desc
+}
+
+// NewCollector 创建一个新的收集器
+// namespace: 指标名前缀，指标名为 <namespace>_window_sum 等
+func NewCollector(namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "window", name), help, []string{"window"}, nil)
+	}
+	return &Collector{
+		windows: make(map[string]*hstat.TimeWindow),
+		sum:     desc("sum", "Sum of all bucket values in the time window."),
+		avg:     desc("avg", "Average of the populated buckets in the time window."),
+		count:   desc("count", "Number of populated buckets in the time window."),
+		min:     desc("min", "Minimum populated bucket value in the time window."),
+		max:     desc("max", "Maximum populated bucket value in the time window."),
+	}
+}
+
+// Add 以指定名称添加一个窗口，同名窗口会被替换
+func (c *Collector) Add(name string, w *hstat.TimeWindow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.windows[name] = w
+}
+
+// Remove 移除指定名称的窗口
+func (c *Collector) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.windows, name)
+}
+
+// Describe 实现 prometheus.Collector 接口
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sum
+	ch <- c.avg
+	ch <- c.count
+	ch <- c.min
+	ch <- c.max
+}
+
+// Collect 实现 prometheus.Collector 接口，每次抓取时读取各窗口的当前聚合值
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for name, w := range c.windows {
+		lo, hi := w.MinMax()
+		ch <- prometheus.MustNewConstMetric(c.sum, prometheus.GaugeValue, w.Sum(), name)
+		ch <- prometheus.MustNewConstMetric(c.avg, prometheus.GaugeValue, w.Avg(), name)
+		ch <- prometheus.MustNewConstMetric(c.count, prometheus.GaugeValue, float64(w.Count()), name)
+		ch <- prometheus.MustNewConstMetric(c.min, prometheus.GaugeValue, lo, name)
+		ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, hi, name)
+	}
+}
@@ -0,0 +1,55 @@
+package hstatprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"pkg.blksails.net/x/hstat"
+)
+
+func TestCollector(t *testing.T) {
+	w := hstat.NewTimeWindow(5, time.Second)
+	w.Inc(3)
+
+	c := NewCollector("app")
+	c.Add("online", w)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			if label := m.GetLabel()[0]; label.GetName() != "window" || label.GetValue() != "online" {
+				t.Errorf("Unexpected label %v on %s", label, mf.GetName())
+			}
+			got[mf.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+
+	want := map[string]float64{
+		"app_window_sum":   3,
+		"app_window_avg":   3,
+		"app_window_count": 1,
+		"app_window_min":   3,
+		"app_window_max":   3,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s: expected %f, got %f", name, v, got[name])
+		}
+	}
+
+	c.Remove("online")
+	if families, _ := reg.Gather(); len(families) != 0 {
+		t.Errorf("Expected no metrics after Remove, got %d families", len(families))
+	}
+}
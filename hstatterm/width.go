@@ -0,0 +1,25 @@
+// Package hstatterm 提供基于终端大小的直方图辅助函数
+// 单独作为子包，使 hstat 核心包不依赖终端库
+package hstatterm
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// WidthFunc 返回一个查询 f 所在终端当前宽度的函数，用于 HistogramOption.WidthFunc
+// 每次绘制时重新查询，因此图表会随终端窗口大小变化；f 不是终端或查询失败时返回 0，直方图退回到默认宽度
+func WidthFunc(f *os.File) func() int {
+	return func() int {
+		fd := int(f.Fd())
+		if !term.IsTerminal(fd) {
+			return 0
+		}
+		width, _, err := term.GetSize(fd)
+		if err != nil {
+			return 0
+		}
+		return width
+	}
+}
@@ -0,0 +1,18 @@
+package hstatterm
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWidthFuncNotTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	if width := WidthFunc(f)(); width != 0 {
+		t.Errorf("Expected width 0 for a regular file, got %d", width)
+	}
+}
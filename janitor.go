@@ -0,0 +1,64 @@
+package hstat
+
+import (
+	"context"
+	"time"
+)
+
+// StartJanitor 启动一个后台 goroutine，每隔一个桶的时间跨度旋转一次窗口，使空闲窗口中过期的桶被及时清空
+// 大多数读取方法在读取前已经按需旋转窗口，只有依赖 Sum 等不旋转的读取、或需要 OnRotate 及时触发时才需要启动。
+// ctx 被取消或调用 StopJanitor 时 goroutine 退出；已经在运行时不产生影响。
+// 间隔在启动时确定，之后通过 SetDuration 修改时间跨度需要重新启动。
+func (w *TimeWindow) StartJanitor(ctx context.Context) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if w.janitorDone != nil {
+		return
+	}
+	interval := w.bucketDuration()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	w.janitorStop, w.janitorDone = cancel, done
+
+	go w.runJanitor(ctx, interval, done)
+}
+
+// StopJanitor 停止 StartJanitor 启动的 goroutine 并等待其退出，未启动时不产生影响
+func (w *TimeWindow) StopJanitor() {
+	w.mu.Lock()
+	stop, done := w.janitorStop, w.janitorDone
+	w.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+func (w *TimeWindow) runJanitor(ctx context.Context, interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+
+		w.mu.Lock()
+		if w.janitorDone == done {
+			w.janitorStop, w.janitorDone = nil, nil
+		}
+		w.mu.Unlock()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.rotate(w.now())
+			w.unlock()
+		}
+	}
+}
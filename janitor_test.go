@@ -0,0 +1,54 @@
+package hstat
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_Janitor(t *testing.T) {
+	w := NewTimeWindow(2, 10*time.Millisecond)
+	w.Inc(5.0)
+
+	w.StartJanitor(context.Background())
+	defer w.StopJanitor()
+
+	// Sum does not rotate by itself; the janitor expires the data
+	deadline := time.Now().Add(2 * time.Second)
+	for w.Sum() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the janitor to expire the data, sum is %f", w.Sum())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w.StopJanitor()
+	w.StopJanitor() // stopping twice is a no-op
+}
+
+func TestTimeWindow_JanitorContext(t *testing.T) {
+	w := NewTimeWindow(2, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.StartJanitor(ctx)
+	cancel()
+
+	// Wait for the goroutine to notice the cancellation
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w.mu.RLock()
+		running := w.janitorDone != nil
+		w.mu.RUnlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the janitor to stop after the context is cancelled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The janitor can be started again after it stopped
+	w.StartJanitor(context.Background())
+	w.StopJanitor()
+}
@@ -0,0 +1,168 @@
+package hstat
+
+import (
+	"sync"
+	"time"
+)
+
+// MultiTimeWindow 表示一个每个桶保存多个序列值的时间窗口
+// 所有序列共用同一组桶，旋转时一起清空，适合记录需要保持同步的多个相关计数，
+// 例如成功、失败和超时的次数。
+type MultiTimeWindow struct {
+	mu         sync.RWMutex
+	values     []float64        // 按桶存储的序列值，桶 i 的序列 s 位于 i*series+s
+	written    []bool           // 每个桶的每个序列在当前生命周期内是否被写入过，布局与 values 相同
+	series     int              // 序列的数量
+	size       int              // 窗口大小(桶的数量)
+	duration   time.Duration    // 每个桶的时间跨度
+	lastTime   time.Time        // 上次更新时间
+	cursor     int              // 当前桶的位置
+	lastUpdate time.Time        // 最近一次数据更新时间
+	clock      func() time.Time // 时钟，为 nil 时使用 time.Now
+}
+
+// NewMultiTimeWindow 创建一个新的多序列时间窗口
+// size: 窗口中桶的数量，< 1 时按1处理
+// duration: 每个桶的时间跨度
+// series: 每个桶中序列的数量，< 1 时按1处理
+// opts 与 NewTimeWindow 的选项相同，但只有 WithClock 生效，其他选项被忽略
+func NewMultiTimeWindow(size int, duration time.Duration, series int, opts ...Option) *MultiTimeWindow {
+	size, series = max(size, 1), max(series, 1)
+	w := &MultiTimeWindow{
+		values:   make([]float64, size*series),
+		written:  make([]bool, size*series),
+		series:   series,
+		size:     size,
+		duration: duration,
+		clock:    clockOption(opts),
+	}
+	w.lastTime = w.now()
+	return w
+}
+
+func (w *MultiTimeWindow) now() time.Time {
+	if w.clock != nil {
+		return w.clock()
+	}
+	return time.Now()
+}
+
+// Inc 将 delta 累加到当前桶的指定序列，series 超出 [0, Series()) 时 panic
+func (w *MultiTimeWindow) Inc(series int, delta float64) {
+	w.checkSeries(series)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.rotate(now)
+	w.lastUpdate = now
+
+	i := w.cursor*w.series + series
+	w.values[i] += delta
+	w.written[i] = true
+}
+
+// Dec 从当前桶的指定序列中减去 delta
+func (w *MultiTimeWindow) Dec(series int, delta float64) {
+	w.Inc(series, -delta)
+}
+
+// rotate 根据时间推移调整窗口，过期桶的所有序列一起清空
+func (w *MultiTimeWindow) rotate(now time.Time) {
+	if w.duration <= 0 {
+		w.duration = defaultDuration
+	}
+	passed := int(now.Sub(w.lastTime) / w.duration)
+	if passed <= 0 {
+		return
+	}
+
+	// 如果经过的时间超过窗口大小，清空所有桶
+	if passed >= w.size {
+		clear(w.values)
+		clear(w.written)
+		w.cursor = 0
+	} else {
+		// 清空过期的桶
+		for i := 0; i < passed; i++ {
+			w.cursor = (w.cursor + 1) % w.size
+			start := w.cursor * w.series
+			clear(w.values[start : start+w.series])
+			clear(w.written[start : start+w.series])
+		}
+	}
+
+	w.lastTime = bucketStart(w.lastTime, now, w.duration, passed)
+}
+
+// Sum 返回指定序列在窗口内所有值的和
+func (w *MultiTimeWindow) Sum(series int) float64 {
+	w.checkSeries(series)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	sum, _ := w.totals(series)
+	return sum
+}
+
+// Avg 返回指定序列在被写入过的桶中的平均值，没有数据时返回 0
+func (w *MultiTimeWindow) Avg(series int) float64 {
+	w.checkSeries(series)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	sum, count := w.totals(series)
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// Sums 在同一次加锁中返回所有序列的和，下标为序列编号
+func (w *MultiTimeWindow) Sums() []float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	sums := make([]float64, w.series)
+	for i, v := range w.values {
+		sums[i%w.series] += v
+	}
+	return sums
+}
+
+// Series 返回每个桶中序列的数量
+func (w *MultiTimeWindow) Series() int {
+	return w.series
+}
+
+// LastUpdateTime 返回最近一次数据更新时间
+func (w *MultiTimeWindow) LastUpdateTime() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastUpdate
+}
+
+// totals 返回指定序列的和以及被写入过的桶的数量
+func (w *MultiTimeWindow) totals(series int) (float64, int) {
+	var sum float64
+	var count int
+	for i := series; i < len(w.values); i += w.series {
+		sum += w.values[i]
+		if w.written[i] {
+			count++
+		}
+	}
+	return sum, count
+}
+
+func (w *MultiTimeWindow) checkSeries(series int) {
+	if series < 0 || series >= w.series {
+		panic("hstat: series index out of range")
+	}
+}
@@ -0,0 +1,85 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMultiTimeWindow_Basic(t *testing.T) {
+	const (
+		success = iota
+		failure
+		timeout
+	)
+
+	clock := newFakeClock()
+	w := NewMultiTimeWindow(3, time.Second, 3, WithClock(clock.Now))
+
+	w.Inc(success, 10)
+	w.Inc(failure, 2)
+	clock.Add(time.Second)
+	w.Inc(success, 20)
+	w.Inc(timeout, 1)
+	w.Dec(success, 4)
+
+	sums := w.Sums()
+	for series, want := range []float64{26, 2, 1} {
+		if got := w.Sum(series); got != want {
+			t.Errorf("Series %d: expected sum %f, got %f", series, want, got)
+		}
+		if sums[series] != want {
+			t.Errorf("Series %d: expected Sums %f, got %f", series, want, sums[series])
+		}
+	}
+
+	// Avg only counts the buckets each series was written in
+	if avg := w.Avg(success); avg != 13 {
+		t.Errorf("Expected success avg 13, got %f", avg)
+	}
+	if avg := w.Avg(failure); avg != 2 {
+		t.Errorf("Expected failure avg 2, got %f", avg)
+	}
+
+	// All series expire together
+	clock.Add(2 * time.Second)
+	if sums := w.Sums(); sums[success] != 16 || sums[failure] != 0 || sums[timeout] != 1 {
+		t.Errorf("Expected sums [16 0 1], got %v", sums)
+	}
+	clock.Add(time.Second)
+	if sums := w.Sums(); sums[success] != 0 || sums[timeout] != 0 {
+		t.Errorf("Expected all series cleared, got %v", sums)
+	}
+}
+
+func TestMultiTimeWindow_SeriesOutOfRange(t *testing.T) {
+	w := NewMultiTimeWindow(3, time.Second, 2)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for out of range series")
+		}
+	}()
+	w.Inc(2, 1)
+}
+
+func TestMultiTimeWindow_InvalidSize(t *testing.T) {
+	// Like NewTimeWindow, non-positive sizes are clamped to one
+	w := NewMultiTimeWindow(0, time.Second, 0)
+	w.Inc(0, 4)
+
+	if sum := w.Sum(0); sum != 4 {
+		t.Errorf("Expected sum 4, got %f", sum)
+	}
+}
+
+func TestMultiTimeWindow_NegativeDuration(t *testing.T) {
+	clock := newFakeClock()
+	w := NewMultiTimeWindow(3, -time.Millisecond, 1, WithClock(clock.Now))
+
+	// A non-positive duration falls back to the default instead of never rotating
+	w.Inc(0, 4)
+	clock.Add(time.Hour)
+	if sum := w.Sum(0); sum != 0 {
+		t.Errorf("Expected the value to expire, got sum %f", sum)
+	}
+}
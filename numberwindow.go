@@ -0,0 +1,181 @@
+package hstat
+
+import (
+	"sync"
+	"time"
+)
+
+// Number 限定 NumberWindow 可用的桶类型
+type Number interface {
+	~int32 | ~int64 | ~float32 | ~float64
+}
+
+// NumberWindow 表示一个桶类型可选的时间窗口
+// 与 TimeWindow 相比只保存桶的值，不记录写入标记，也不支持钩子、前缀和等扩展功能；
+// 使用 int32 或 float32 时每个桶只占4个字节，不到 TimeWindow 的一半，适合大量长窗口的计数场景，
+// 使用 int64 时计数不会有浮点误差。值为0的桶视为空桶，不参与 Count、Avg、Min 和 Max。
+type NumberWindow[T Number] struct {
+	mu         sync.RWMutex
+	buckets    []T
+	size       int              // 窗口大小(桶的数量)
+	duration   time.Duration    // 每个桶的时间跨度
+	lastTime   time.Time        // 当前桶的起始时间
+	cursor     int              // 当前桶的位置
+	lastUpdate time.Time        // 最近一次数据更新时间
+	clock      func() time.Time // 时钟，为 nil 时使用 time.Now
+}
+
+// NewNumberWindow 创建一个新的时间窗口，参数与 NewTimeWindow 相同
+// size < 1 时按1处理，duration <= 0 时使用默认值5分钟；opts 中只有 WithClock 生效，其他选项被忽略
+func NewNumberWindow[T Number](size int, duration time.Duration, opts ...Option) *NumberWindow[T] {
+	size = max(size, 1)
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+	w := &NumberWindow[T]{
+		buckets:  make([]T, size),
+		size:     size,
+		duration: duration,
+		clock:    clockOption(opts),
+	}
+	w.lastTime = w.now()
+	return w
+}
+
+func (w *NumberWindow[T]) now() time.Time {
+	if w.clock != nil {
+		return w.clock()
+	}
+	return time.Now()
+}
+
+// Inc 在当前桶中累加值
+func (w *NumberWindow[T]) Inc(delta T) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.rotate(now)
+	w.lastUpdate = laterTime(w.lastUpdate, now)
+	w.buckets[w.cursor] += delta
+}
+
+// Dec 在当前桶中递减值
+func (w *NumberWindow[T]) Dec(delta T) {
+	w.Inc(-delta)
+}
+
+// rotate 根据时间推移调整窗口
+func (w *NumberWindow[T]) rotate(now time.Time) {
+	passed := int(now.Sub(w.lastTime) / w.duration)
+	if passed <= 0 {
+		return
+	}
+	w.cursor = advanceRing(w.buckets, w.cursor, passed)
+	w.lastTime = bucketStart(w.lastTime, now, w.duration, passed)
+}
+
+// Sum 返回窗口内所有桶的和
+func (w *NumberWindow[T]) Sum() T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	var sum T
+	for _, v := range w.buckets {
+		sum += v
+	}
+	return sum
+}
+
+// Count 返回窗口内非零的桶的数量
+func (w *NumberWindow[T]) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	var count int
+	for _, v := range w.buckets {
+		if v != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Avg 返回窗口内非零的桶的平均值，窗口为空时返回 0
+func (w *NumberWindow[T]) Avg() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	var sum float64
+	var count int
+	for _, v := range w.buckets {
+		if v != 0 {
+			sum += float64(v)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// MinMax 返回窗口内非零的桶中的最小值和最大值，窗口为空时都返回 0
+func (w *NumberWindow[T]) MinMax() (T, T) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	var minValue, maxValue T
+	found := false
+	for _, v := range w.buckets {
+		if v == 0 {
+			continue
+		}
+		if !found {
+			minValue, maxValue = v, v
+			found = true
+			continue
+		}
+		minValue = min(minValue, v)
+		maxValue = max(maxValue, v)
+	}
+	return minValue, maxValue
+}
+
+// GetValueAt 返回指定年龄的桶的值，age为0表示当前桶，超出范围时返回 0, false
+func (w *NumberWindow[T]) GetValueAt(age int) (T, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	if age < 0 || age >= w.size {
+		return 0, false
+	}
+	return w.buckets[(w.cursor-age+w.size)%w.size], true
+}
+
+// LastUpdateTime 返回最近一次数据更新时间
+func (w *NumberWindow[T]) LastUpdateTime() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastUpdate
+}
+
+// advanceRing 将环形缓冲区的游标前进 passed 个桶并清空经过的桶，返回新的游标
+// passed 不小于桶数量时清空所有桶，游标回到0
+func advanceRing[T any](buckets []T, cursor, passed int) int {
+	if passed >= len(buckets) {
+		clear(buckets)
+		return 0
+	}
+	var zero T
+	for i := 0; i < passed; i++ {
+		cursor = (cursor + 1) % len(buckets)
+		buckets[cursor] = zero
+	}
+	return cursor
+}
@@ -0,0 +1,86 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestNumberWindow[T Number](clock *fakeClock, size int) *NumberWindow[T] {
+	return NewNumberWindow[T](size, time.Second, WithClock(clock.Now))
+}
+
+func TestNumberWindow_Int64(t *testing.T) {
+	clock := newFakeClock()
+	w := newTestNumberWindow[int64](clock, 3)
+
+	w.Inc(5)
+	w.Inc(2)
+	clock.Add(time.Second)
+	w.Inc(10)
+	w.Dec(1)
+
+	if sum := w.Sum(); sum != 16 {
+		t.Errorf("Expected sum 16, got %d", sum)
+	}
+	if count := w.Count(); count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+	if avg := w.Avg(); avg != 8 {
+		t.Errorf("Expected average 8, got %f", avg)
+	}
+	if lo, hi := w.MinMax(); lo != 7 || hi != 9 {
+		t.Errorf("Expected min 7 max 9, got %d and %d", lo, hi)
+	}
+	if v, ok := w.GetValueAt(1); !ok || v != 7 {
+		t.Errorf("Expected 7 at age 1, got %d", v)
+	}
+
+	// Buckets expire like TimeWindow
+	clock.Add(2 * time.Second)
+	if sum := w.Sum(); sum != 9 {
+		t.Errorf("Expected sum 9 after expiry, got %d", sum)
+	}
+	clock.Add(time.Hour)
+	if sum := w.Sum(); sum != 0 {
+		t.Errorf("Expected sum 0 after full expiry, got %d", sum)
+	}
+}
+
+func TestNumberWindow_Float32(t *testing.T) {
+	clock := newFakeClock()
+	w := newTestNumberWindow[float32](clock, 4)
+
+	w.Inc(1.5)
+	clock.Add(time.Second)
+	w.Inc(2.5)
+
+	if sum := w.Sum(); sum != 4 {
+		t.Errorf("Expected sum 4, got %f", sum)
+	}
+	if !w.LastUpdateTime().Equal(clock.Now()) {
+		t.Errorf("Expected last update %v, got %v", clock.Now(), w.LastUpdateTime())
+	}
+}
+
+// The memory benchmarks report the per-window footprint in B/op
+
+func BenchmarkTimeWindow_New3600(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewTimeWindow(3600, time.Second)
+	}
+}
+
+func BenchmarkNumberWindow_NewInt64x3600(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewNumberWindow[int64](3600, time.Second)
+	}
+}
+
+func BenchmarkNumberWindow_NewInt32x3600(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewNumberWindow[int32](3600, time.Second)
+	}
+}
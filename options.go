@@ -0,0 +1,48 @@
+package hstat
+
+import "time"
+
+// Option 用于在创建时间窗口时配置可选行为
+type Option func(*TimeWindow)
+
+// WithClock 设置窗口使用的时钟，默认使用 time.Now
+// 主要用于测试和回放数据时控制时间
+func WithClock(now func() time.Time) Option {
+	return func(w *TimeWindow) {
+		w.clock = now
+	}
+}
+
+// clockOption 返回 opts 中 WithClock 设置的时钟，未设置时返回 nil
+// 供只支持 WithClock 的其他窗口类型复用 Option
+func clockOption(opts []Option) func() time.Time {
+	var cfg TimeWindow
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.clock
+}
+
+// WithPrefixSums 启用前缀和缓存，使 SumRange 的查询复杂度为 O(1)
+// 代价是每次写入需要 O(size) 时间更新缓存，并额外占用 size+1 个 float64，
+// 适用于读远多于写、频繁按区间求和的场景
+func WithPrefixSums() Option {
+	return func(w *TimeWindow) {
+		w.prefix = make([]float64, w.size+1)
+	}
+}
+
+// WithSampleCounts 启用每个桶的写入次数统计，用于 SampleCount 和 SampleAvg
+// 代价是额外占用 size 个 int，每次写入多一次计数
+func WithSampleCounts() Option {
+	return func(w *TimeWindow) {
+		w.counts = make([]int, w.size)
+	}
+}
+
+// WithAggMode 设置 Add 在同一个桶内多次写入时的聚合方式，默认为 AggSum
+func WithAggMode(mode AggMode) Option {
+	return func(w *TimeWindow) {
+		w.aggMode = mode
+	}
+}
@@ -0,0 +1,37 @@
+package hstat
+
+// Pause 暂停窗口的旋转，暂停期间桶不会过期
+// 暂停前先将窗口旋转到当前时间；暂停期间的写入都落在当前桶。重复调用不产生影响。
+func (w *TimeWindow) Pause() {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if w.paused {
+		return
+	}
+	now := w.now()
+	w.rotate(now)
+	w.paused = true
+	w.pausedAt = now
+}
+
+// Resume 恢复窗口的旋转
+// 当前桶的起始时间顺延暂停的时长，恢复后不会因为暂停期间经过的时间而清空桶。未暂停时不产生影响。
+func (w *TimeWindow) Resume() {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if !w.paused {
+		return
+	}
+	w.lastTime = w.lastTime.Add(w.now().Sub(w.pausedAt))
+	w.paused = false
+}
+
+// Paused 返回窗口是否处于暂停状态
+func (w *TimeWindow) Paused() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.paused
+}
@@ -0,0 +1,61 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_PauseResume(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	clock.Add(500 * time.Millisecond)
+
+	w.Pause()
+	if !w.Paused() {
+		t.Error("Expected window to be paused")
+	}
+
+	// Several bucket durations pass while paused; writes land in the frozen bucket
+	clock.Add(10 * time.Second)
+	w.Inc(3.0)
+	if val, _ := w.GetLatestValue(); val != 5.0 {
+		t.Errorf("Expected current bucket 5.0 while paused, got %f", val)
+	}
+	if elapsed := w.CurrentBucketElapsed(); elapsed != 500*time.Millisecond {
+		t.Errorf("Expected elapsed to stay at 500ms while paused, got %s", elapsed)
+	}
+
+	w.Resume()
+	if w.Paused() {
+		t.Error("Expected window to be resumed")
+	}
+	if sum := w.Sum(); sum != 6.0 {
+		t.Errorf("Expected no data lost after resume, got sum %f", sum)
+	}
+
+	// Rotation picks up where it stopped: 500ms more finishes the current bucket
+	clock.Add(500 * time.Millisecond)
+	w.Inc(4.0)
+	for age, want := range []float64{4, 5, 1} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+}
+
+func TestTimeWindow_ResumeWithoutPause(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+
+	w.Resume()
+	clock.Add(time.Second)
+	w.Inc(2.0)
+
+	if sum := w.Sum(); sum != 3.0 {
+		t.Errorf("Expected sum 3.0, got %f", sum)
+	}
+}
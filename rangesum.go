@@ -0,0 +1,72 @@
+package hstat
+
+// SumRange 计算年龄在 [from, to] 范围内的桶的和，年龄0为当前桶
+// 范围会被截断到 [0, size-1]；启用 WithPrefixSums 时复杂度为 O(1)，否则为 O(to-from)
+func (w *TimeWindow) SumRange(from, to int) float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.sumRange(from, to)
+}
+
+// SumLast 返回最近 n 个桶(年龄 0 到 n-1)的和，n 会被截断到 [1, size]
+func (w *TimeWindow) SumLast(n int) float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.sumRange(0, max(n, 1)-1)
+}
+
+// AvgLast 返回最近 n 个桶中被写入过的桶的平均值，n 会被截断到 [1, size]
+// 与 Avg 一样只按被写入过的桶计数，这些桶都没有数据时返回 0
+func (w *TimeWindow) AvgLast(n int) float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	n = min(max(n, 1), w.size)
+
+	count := 0
+	for age := 0; age < n; age++ {
+		if w.populated((w.cursor - age + w.size) % w.size) {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return w.sumRange(0, n-1) / float64(count)
+}
+
+func (w *TimeWindow) sumRange(from, to int) float64 {
+	from = max(from, 0)
+	to = min(to, w.size-1)
+	if from > to {
+		return 0
+	}
+
+	if w.prefix == nil {
+		var sum float64
+		for age := from; age <= to; age++ {
+			sum += w.buckets[(w.cursor-age+w.size)%w.size]
+		}
+		return sum
+	}
+
+	// 年龄越大，存储位置越靠前；区间可能跨越切片末尾
+	start := (w.cursor - to + w.size) % w.size
+	end := (w.cursor - from + w.size) % w.size
+	if start <= end {
+		return w.prefix[end+1] - w.prefix[start]
+	}
+	return w.prefix[w.size] - w.prefix[start] + w.prefix[end+1]
+}
+
+// updatePrefix 从存储位置 idx 开始重新计算前缀和
+func (w *TimeWindow) updatePrefix(idx int) {
+	for i := idx; i < w.size; i++ {
+		w.prefix[i+1] = w.prefix[i] + w.buckets[i]
+	}
+}
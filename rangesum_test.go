@@ -0,0 +1,119 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_SumRange(t *testing.T) {
+	for _, prefix := range []bool{false, true} {
+		clock := newFakeClock()
+		opts := []Option{WithClock(clock.Now)}
+		if prefix {
+			opts = append(opts, WithPrefixSums())
+		}
+		w := NewTimeWindow(5, time.Second, opts...)
+
+		// Wrap the cursor so ranges straddle the end of the slice; ages 0..4 hold 7..3
+		for i := 1; i <= 7; i++ {
+			w.Inc(float64(i))
+			clock.Add(time.Second)
+		}
+		clock.Add(-time.Second)
+
+		cases := []struct {
+			from, to int
+			want     float64
+		}{
+			{0, 0, 7},
+			{0, 4, 25},
+			{1, 3, 15},
+			{3, 4, 7},
+			{-2, 1, 13},
+			{3, 10, 7},
+			{4, 2, 0},
+		}
+		for _, c := range cases {
+			if got := w.SumRange(c.from, c.to); got != c.want {
+				t.Errorf("prefix=%v SumRange(%d, %d): expected %f, got %f", prefix, c.from, c.to, c.want, got)
+			}
+		}
+	}
+}
+
+func TestTimeWindow_SumRangeAfterRotation(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now), WithPrefixSums())
+
+	w.Inc(1.0)
+	clock.Add(2 * time.Second)
+	w.Inc(2.0)
+
+	if sum := w.SumRange(0, 4); sum != 3.0 {
+		t.Errorf("Expected sum 3.0, got %f", sum)
+	}
+
+	clock.Add(4 * time.Second)
+	if sum := w.SumRange(0, 4); sum != 2.0 {
+		t.Errorf("Expected sum 2.0 after expiry, got %f", sum)
+	}
+}
+
+// Benchmarks
+
+func benchmarkSumRange(b *testing.B, opts ...Option) {
+	w := NewTimeWindow(3600, time.Hour, opts...)
+	for i := 0; i < 3600; i++ {
+		w.buckets[i] = float64(i)
+	}
+	w.recompute()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := i % 3600
+		w.SumRange(n/2, n)
+	}
+}
+
+func BenchmarkTimeWindow_SumRange(b *testing.B) {
+	benchmarkSumRange(b)
+}
+
+func BenchmarkTimeWindow_SumRangePrefixSums(b *testing.B) {
+	benchmarkSumRange(b, WithPrefixSums())
+}
+
+func BenchmarkTimeWindow_IncPrefixSums(b *testing.B) {
+	w := NewTimeWindow(3600, time.Second, WithPrefixSums())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Inc(1.0)
+	}
+}
+
+func TestTimeWindow_SumLastAvgLast(t *testing.T) {
+	// Oldest to newest; the zero is an unwritten gap
+	w := newFilledWindow(newFakeClock(), 8, 6, 0, 4, 2)
+
+	cases := []struct {
+		n        int
+		sum, avg float64
+	}{
+		{0, 2, 2},  // clamped to 1
+		{2, 6, 3},  // smaller than size
+		{3, 6, 3},  // the gap does not count towards the average
+		{5, 20, 5}, // equal to size
+		{9, 20, 5}, // larger than size
+	}
+	for _, c := range cases {
+		if sum := w.SumLast(c.n); sum != c.sum {
+			t.Errorf("SumLast(%d): expected %f, got %f", c.n, c.sum, sum)
+		}
+		if avg := w.AvgLast(c.n); avg != c.avg {
+			t.Errorf("AvgLast(%d): expected %f, got %f", c.n, c.avg, avg)
+		}
+	}
+
+	if avg := NewTimeWindow(3, time.Second).AvgLast(2); avg != 0 {
+		t.Errorf("Expected 0 for empty window, got %f", avg)
+	}
+}
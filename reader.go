@@ -0,0 +1,19 @@
+package hstat
+
+import "time"
+
+// Reader 是时间窗口的只读视图，*TimeWindow 实现了该接口
+// 只需要读取窗口的代码(如仪表盘)可以接收 Reader，在类型上避免误调用 Inc、Dec 等写入方法。
+// 部分读取方法仍会将窗口旋转到当前时间，这只会清空过期的桶，不会写入数据
+type Reader interface {
+	Sum() float64
+	Avg() float64
+	Count() int
+	Min() float64
+	Max() float64
+	GetData() []TimeWindowData
+	LastUpdateTime() time.Time
+	PrintHistogram(opt *HistogramOption) string
+}
+
+var _ Reader = (*TimeWindow)(nil)
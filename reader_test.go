@@ -0,0 +1,22 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+// summarize only needs read access to a window
+func summarize(r Reader) (float64, int) {
+	return r.Sum(), r.Count()
+}
+
+func TestReader(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(2.0)
+	w.Inc(3.0)
+
+	sum, count := summarize(w)
+	if sum != 5.0 || count != 1 {
+		t.Errorf("Expected sum 5.0 count 1, got sum %f count %d", sum, count)
+	}
+}
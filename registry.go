@@ -0,0 +1,77 @@
+package hstat
+
+import (
+	"sync"
+	"time"
+)
+
+// Registry 按名称管理多个时间窗口，可以安全地并发使用
+type Registry struct {
+	mu      sync.RWMutex
+	windows map[string]*TimeWindow
+}
+
+// NewRegistry 创建一个新的窗口注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		windows: make(map[string]*TimeWindow),
+	}
+}
+
+// GetOrCreate 返回指定名称的窗口，不存在时用给定参数创建
+// 已存在的窗口原样返回，即使 size、duration 或 opts 与创建时不同也不会修改或报错
+func (r *Registry) GetOrCreate(name string, size int, duration time.Duration, opts ...Option) *TimeWindow {
+	r.mu.RLock()
+	w, ok := r.windows[name]
+	r.mu.RUnlock()
+	if ok {
+		return w
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 加写锁期间可能已被其他 goroutine 创建
+	if w, ok := r.windows[name]; ok {
+		return w
+	}
+	w = NewTimeWindow(size, duration, opts...)
+	r.windows[name] = w
+	return w
+}
+
+// Get 返回指定名称的窗口，不存在时返回 false
+func (r *Registry) Get(name string) (*TimeWindow, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.windows[name]
+	return w, ok
+}
+
+// Delete 移除指定名称的窗口
+func (r *Registry) Delete(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.windows, name)
+}
+
+// Range 依次对每个窗口调用 f，f 返回 false 时停止遍历
+// 遍历的是调用时的窗口快照，顺序不确定；f 在锁外执行，可以在其中调用 Registry 的其他方法
+func (r *Registry) Range(f func(name string, w *TimeWindow) bool) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.windows))
+	windows := make([]*TimeWindow, 0, len(r.windows))
+	for name, w := range r.windows {
+		names = append(names, name)
+		windows = append(windows, w)
+	}
+	r.mu.RUnlock()
+
+	for i, name := range names {
+		if !f(name, windows[i]) {
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package hstat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry_GetOrCreate(t *testing.T) {
+	r := NewRegistry()
+
+	a := r.GetOrCreate("requests", 60, time.Second)
+	a.Inc(1)
+
+	// An existing window is returned unchanged, even with different parameters
+	b := r.GetOrCreate("requests", 10, time.Minute)
+	if a != b {
+		t.Error("Expected the same window for an existing name")
+	}
+	if size := b.Header().Size; size != 60 {
+		t.Errorf("Expected original size 60, got %d", size)
+	}
+
+	if w, ok := r.Get("requests"); !ok || w != a {
+		t.Error("Expected Get to return the registered window")
+	}
+
+	r.Delete("requests")
+	if _, ok := r.Get("requests"); ok {
+		t.Error("Expected window to be deleted")
+	}
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.GetOrCreate("shared", 5, time.Second).Inc(1)
+		}()
+	}
+	wg.Wait()
+
+	w, _ := r.Get("shared")
+	if sum := w.Sum(); sum != 50 {
+		t.Errorf("Expected all increments on one window, got sum %f", sum)
+	}
+}
+
+func TestRegistry_Range(t *testing.T) {
+	r := NewRegistry()
+	r.GetOrCreate("a", 5, time.Second)
+	r.GetOrCreate("b", 5, time.Second)
+	r.GetOrCreate("c", 5, time.Second)
+
+	seen := make(map[string]bool)
+	r.Range(func(name string, w *TimeWindow) bool {
+		seen[name] = true
+		r.Delete(name)
+		return true
+	})
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 windows, got %v", seen)
+	}
+
+	r.GetOrCreate("a", 5, time.Second)
+	r.GetOrCreate("b", 5, time.Second)
+	calls := 0
+	r.Range(func(string, *TimeWindow) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected Range to stop after 1 call, got %d", calls)
+	}
+}
@@ -0,0 +1,42 @@
+package hstat
+
+// countSamples 将位置 idx 的桶的写入次数加 n，未启用 WithSampleCounts 时不做任何操作
+func (w *TimeWindow) countSamples(idx, n int) {
+	if w.counts != nil {
+		w.counts[idx] += n
+	}
+}
+
+// SampleCount 返回窗口内未过期的桶的写入次数之和，需要创建时启用 WithSampleCounts，否则返回 0
+// Inc、Dec、Append、Add 及其 *At 变体每次调用计一次，IncBatch 和 AppendBatch 按元素个数计；
+// Merge 累加来源窗口的写入次数(来源未启用时每个合并的桶计一次)；Reset、ResetAll 和 Clear 不计入，Clear 和 ResetAll 会将计数清零。
+func (w *TimeWindow) SampleCount() int {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.sampleCount()
+}
+
+func (w *TimeWindow) sampleCount() int {
+	var n int
+	for _, c := range w.counts {
+		n += c
+	}
+	return n
+}
+
+// SampleAvg 返回每次写入的平均值，即 Sum 除以 SampleCount
+// 与按桶平均的 Avg 不同，同一个桶内多次 Inc 的每个样本权重相同，如每个请求的平均延迟而不是每秒的平均延迟。
+// 没有样本或未启用 WithSampleCounts 时返回 0
+func (w *TimeWindow) SampleAvg() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	n := w.sampleCount()
+	if n == 0 {
+		return 0
+	}
+	return w.sum / float64(n)
+}
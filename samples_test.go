@@ -0,0 +1,76 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_SampleCounts(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now), WithSampleCounts())
+
+	// Three requests in the first second, one in the next
+	w.Inc(10)
+	w.Inc(20)
+	w.Inc(30)
+	clock.Add(time.Second)
+	w.Inc(100)
+
+	if n := w.SampleCount(); n != 4 {
+		t.Errorf("Expected 4 samples, got %d", n)
+	}
+	if avg := w.SampleAvg(); avg != 40 {
+		t.Errorf("Expected sample average 40, got %f", avg)
+	}
+	// The per-bucket average weighs each second equally
+	if avg := w.Avg(); avg != 80 {
+		t.Errorf("Expected bucket average 80, got %f", avg)
+	}
+
+	w.IncBatch([]float64{1, 2})
+	if n := w.SampleCount(); n != 6 {
+		t.Errorf("Expected 6 samples after a batch, got %d", n)
+	}
+
+	// Counts expire with their buckets
+	clock.Add(3 * time.Second)
+	if n := w.SampleCount(); n != 0 {
+		t.Errorf("Expected 0 samples after expiry, got %d", n)
+	}
+	if avg := w.SampleAvg(); avg != 0 {
+		t.Errorf("Expected sample average 0 with no samples, got %f", avg)
+	}
+}
+
+func TestTimeWindow_SampleCountsResize(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now), WithSampleCounts())
+	w.Inc(1)
+	clock.Add(time.Second)
+	w.Inc(1)
+	w.Inc(1)
+
+	if err := w.Resize(5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := w.SampleCount(); n != 3 {
+		t.Errorf("Expected 3 samples after resize, got %d", n)
+	}
+
+	w.Clear()
+	if n := w.SampleCount(); n != 0 {
+		t.Errorf("Expected 0 samples after Clear, got %d", n)
+	}
+}
+
+func TestTimeWindow_SampleCountsDisabled(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+	w.Inc(5)
+
+	if n := w.SampleCount(); n != 0 {
+		t.Errorf("Expected 0 samples without WithSampleCounts, got %d", n)
+	}
+	if avg := w.SampleAvg(); avg != 0 {
+		t.Errorf("Expected sample average 0 without WithSampleCounts, got %f", avg)
+	}
+}
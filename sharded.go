@@ -0,0 +1,113 @@
+package hstat
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"time"
+)
+
+// ShardedTimeWindow 由多个独立的 TimeWindow 分片组成，用于高并发写入
+// 写操作随机分配到各个分片，减少单个窗口上的锁竞争；读操作把所有分片旋转到同一时间，
+// 按年龄对齐后相加，因此各分片的游标位置不同也不影响结果。
+// 分片的值按累加合并，只适用于 AggSum 聚合方式。
+type ShardedTimeWindow struct {
+	shards []*TimeWindow
+}
+
+// NewShardedTimeWindow 创建一个包含 shards 个分片的窗口，每个分片都用 size、duration 和 opts 创建
+// shards <= 0 时使用 runtime.GOMAXPROCS(0) 个分片
+func NewShardedTimeWindow(shards, size int, duration time.Duration, opts ...Option) *ShardedTimeWindow {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &ShardedTimeWindow{
+		shards: make([]*TimeWindow, shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewTimeWindow(size, duration, opts...)
+	}
+	return s
+}
+
+// shard 随机选择一个写入的分片
+// 与共享的轮询计数器相比，随机选择不需要在各个写入者之间同步
+func (s *ShardedTimeWindow) shard() *TimeWindow {
+	return s.shards[rand.IntN(len(s.shards))]
+}
+
+// Inc 将 delta 累加到某个分片的当前桶
+func (s *ShardedTimeWindow) Inc(delta float64) {
+	s.shard().Inc(delta)
+}
+
+// Dec 从某个分片的当前桶中减去 delta
+func (s *ShardedTimeWindow) Dec(delta float64) {
+	s.shard().Dec(delta)
+}
+
+// Sum 返回所有分片窗口内的值之和
+func (s *ShardedTimeWindow) Sum() float64 {
+	var sum float64
+	for _, v := range s.merged().values {
+		sum += v
+	}
+	return sum
+}
+
+// Count 返回任一分片被写入过的桶的数量，同一年龄的桶只计一次
+func (s *ShardedTimeWindow) Count() int {
+	count := 0
+	for _, written := range s.merged().written {
+		if written {
+			count++
+		}
+	}
+	return count
+}
+
+// Avg 返回合并后被写入过的桶的平均值
+func (s *ShardedTimeWindow) Avg() float64 {
+	merged := s.merged()
+
+	var sum float64
+	count := 0
+	for i, v := range merged.values {
+		if merged.written[i] {
+			sum += v
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// Snapshot 将所有分片合并为一个独立的 TimeWindow 返回
+func (s *ShardedTimeWindow) Snapshot() *TimeWindow {
+	return s.shards[0].newByAge(s.merged())
+}
+
+// Shards 返回分片的数量
+func (s *ShardedTimeWindow) Shards() int {
+	return len(s.shards)
+}
+
+func (s *ShardedTimeWindow) merged() ageView {
+	return s.mergedAt(s.shards[0].now())
+}
+
+// mergedAt 将所有分片旋转到 now 后按年龄对齐相加
+func (s *ShardedTimeWindow) mergedAt(now time.Time) ageView {
+	merged := s.shards[0].byAge(now)
+	for _, shard := range s.shards[1:] {
+		v := shard.byAge(now)
+		for age := range merged.values {
+			merged.values[age] += v.values[age]
+			merged.written[age] = merged.written[age] || v.written[age]
+		}
+		merged.lastUpdate = laterTime(merged.lastUpdate, v.lastUpdate)
+	}
+	return merged
+}
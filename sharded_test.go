@@ -0,0 +1,90 @@
+package hstat
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedTimeWindow_Basic(t *testing.T) {
+	clock := newFakeClock()
+	s := NewShardedTimeWindow(3, 4, time.Second, WithClock(clock.Now))
+
+	// Writes land in random shards but line up in the same bucket by age
+	s.Inc(1.0)
+	s.Inc(2.0)
+	s.Inc(3.0)
+	s.Inc(4.0)
+	clock.Add(time.Second)
+	s.Inc(5.0)
+	s.Dec(1.0)
+
+	if sum := s.Sum(); sum != 14.0 {
+		t.Errorf("Expected sum 14.0, got %f", sum)
+	}
+	if count := s.Count(); count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+	if avg := s.Avg(); avg != 7.0 {
+		t.Errorf("Expected avg 7.0, got %f", avg)
+	}
+
+	snapshot := s.Snapshot()
+	for age, want := range []float64{4, 10, 0, 0} {
+		if val, _ := snapshot.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+
+	// Old data expires from every shard
+	clock.Add(4 * time.Second)
+	if sum := s.Sum(); sum != 0 {
+		t.Errorf("Expected sum 0 after expiry, got %f", sum)
+	}
+}
+
+func TestShardedTimeWindow_DefaultShards(t *testing.T) {
+	s := NewShardedTimeWindow(0, 4, time.Second)
+	if s.Shards() != runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected %d shards, got %d", runtime.GOMAXPROCS(0), s.Shards())
+	}
+}
+
+func TestShardedTimeWindow_Concurrent(t *testing.T) {
+	s := NewShardedTimeWindow(4, 60, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Inc(1.0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sum := s.Sum(); sum != 8000 {
+		t.Errorf("Expected sum 8000, got %f", sum)
+	}
+}
+
+func BenchmarkTimeWindow_ParallelInc(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w.Inc(1.0)
+		}
+	})
+}
+
+func BenchmarkShardedTimeWindow_ParallelInc(b *testing.B) {
+	s := NewShardedTimeWindow(0, 60, time.Second)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Inc(1.0)
+		}
+	})
+}
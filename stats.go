@@ -0,0 +1,450 @@
+package hstat
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// IsMonotonic 判断窗口内的数据从旧到新是否单调
+// increasing 表示非递减，decreasing 表示非递增，strict 表示成立的方向是否严格单调；
+// 所有值相等时 increasing 和 decreasing 同时为 true，strict 为 false。
+// 空桶(参见 Count)不参与判断；有数据的桶少于两个时无法判断趋势，全部返回 false。
+func (w *TimeWindow) IsMonotonic() (increasing, decreasing, strict bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	increasing, decreasing = true, true
+	strictInc, strictDec := true, true
+	var prev float64
+	n := 0
+	for age := w.size - 1; age >= 0; age-- {
+		idx := (w.cursor - age + w.size) % w.size
+		if !w.populated(idx) {
+			continue
+		}
+		v := w.buckets[idx]
+		if n > 0 {
+			if v < prev {
+				increasing, strictInc = false, false
+			} else if v == prev {
+				strictInc, strictDec = false, false
+			}
+			if v > prev {
+				decreasing, strictDec = false, false
+			}
+		}
+		prev = v
+		n++
+	}
+
+	if n < 2 {
+		return false, false, false
+	}
+	return increasing, decreasing, (increasing && strictInc) || (decreasing && strictDec)
+}
+
+// Min 返回窗口内有数据的桶中的最小值，窗口为空时返回 0
+// 与 Count 一致，空桶不参与计算
+func (w *TimeWindow) Min() float64 {
+	minValue, _ := w.MinMax()
+	return minValue
+}
+
+// Max 返回窗口内有数据的桶中的最大值，窗口为空时返回 0
+// 与 Count 一致，空桶不参与计算
+func (w *TimeWindow) Max() float64 {
+	_, maxValue := w.MinMax()
+	return maxValue
+}
+
+// MinMax 在一次遍历中同时返回最小值和最大值，参见 Min 和 Max
+func (w *TimeWindow) MinMax() (float64, float64) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.minMax()
+}
+
+func (w *TimeWindow) minMax() (minValue, maxValue float64) {
+	found := false
+	for i, v := range w.buckets {
+		if !w.populated(i) {
+			continue
+		}
+		if !found {
+			minValue, maxValue = v, v
+			found = true
+			continue
+		}
+		minValue = min(minValue, v)
+		maxValue = max(maxValue, v)
+	}
+	return minValue, maxValue
+}
+
+// Percentile 返回窗口内有数据的桶的第 p 百分位数，p 会被截断到 [0,100]，p 为 NaN 时返回 NaN
+// 使用相邻两个值之间的线性插值；空桶(参见 Count)不计入分布，窗口为空时返回 0
+func (w *TimeWindow) Percentile(p float64) float64 {
+	return w.Percentiles(p)[0]
+}
+
+// Percentiles 在一次排序中返回多个百分位数，结果与 ps 的顺序一一对应，参见 Percentile
+func (w *TimeWindow) Percentiles(ps ...float64) []float64 {
+	w.mu.Lock()
+	values := w.populatedValues()
+	w.unlock()
+
+	sort.Float64s(values)
+
+	result := make([]float64, len(ps))
+	for i, p := range ps {
+		result[i] = percentile(values, p)
+	}
+	return result
+}
+
+// Median 返回窗口内有数据的桶的中位数，有数据的桶为偶数个时取中间两个值的平均值
+// 空桶(参见 Count)不计入，窗口为空时返回 0
+func (w *TimeWindow) Median() float64 {
+	w.mu.Lock()
+	values := w.populatedValues()
+	w.unlock()
+
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// HistBin 是 ValueHistogram 中的一个区间，覆盖 [Lo, Hi)，最后一个区间包含 Hi
+type HistBin struct {
+	Lo    float64
+	Hi    float64
+	Count int
+}
+
+// ValueHistogram 统计窗口内有数据的桶的值的分布，将 [最小值, 最大值] 等分为 bins 个区间并计数
+// 与 PrintHistogram 按时间绘制不同，它回答的是值落在各个区间的频数；所有值都相同时区间为该值上下各0.5。
+// 空桶(参见 Count)和非有限值(±Inf、NaN)不计入，没有可统计的值或 bins <= 0 时返回空切片
+func (w *TimeWindow) ValueHistogram(bins int) []HistBin {
+	if bins <= 0 {
+		return []HistBin{}
+	}
+
+	w.mu.Lock()
+	values := slices.DeleteFunc(w.populatedValues(), func(v float64) bool {
+		return math.IsInf(v, 0) || math.IsNaN(v)
+	})
+	w.unlock()
+
+	if len(values) == 0 {
+		return []HistBin{}
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if lo == hi {
+		lo, hi = lo-0.5, hi+0.5
+	}
+
+	// hi-lo 可能超出 float64 的范围，因此按半宽计算：减半是精确的，结果与直接相减相同
+	halfWidth := (hi/2 - lo/2) / float64(bins)
+	edge := func(i int) float64 {
+		d := float64(i) * halfWidth
+		if e := lo + 2*d; !math.IsInf(e, 0) {
+			return e
+		}
+		return lo + d + d
+	}
+
+	result := make([]HistBin, bins)
+	for i := range result {
+		result[i].Lo = edge(i)
+		result[i].Hi = edge(i + 1)
+	}
+	result[bins-1].Hi = hi
+
+	for _, v := range values {
+		i := int((v/2 - lo/2) / halfWidth)
+		result[min(max(i, 0), bins-1)].Count++
+	}
+	return result
+}
+
+// populatedValues 旋转窗口后复制所有有数据的桶的值，按存储顺序排列
+func (w *TimeWindow) populatedValues() []float64 {
+	w.rotate(w.now())
+
+	values := make([]float64, 0, w.size)
+	for i, v := range w.buckets {
+		if w.populated(i) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// percentile 计算已排序数据的第 p 百分位数，p 为 NaN 时返回 NaN
+func percentile(sorted []float64, p float64) float64 {
+	if math.IsNaN(p) {
+		return math.NaN()
+	}
+	if len(sorted) == 0 {
+		return 0
+	}
+	p = min(max(p, 0), 100)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
+
+// TrimmedMean 返回去掉两端极值后的平均值
+// 有数据的桶排序后，从两端各去掉 floor(fraction*N) 个值，再对剩余的值求平均；fraction 为0时即普通平均值。
+// 空桶(参见 Count)不参与计算；窗口为空时返回 0，fraction 不在 [0,0.5) 范围内或为 NaN 时返回 NaN。
+func (w *TimeWindow) TrimmedMean(fraction float64) float64 {
+	if !(fraction >= 0 && fraction < 0.5) {
+		return math.NaN()
+	}
+
+	w.mu.Lock()
+	values := w.populatedValues()
+	w.unlock()
+
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+
+	k := int(fraction * float64(len(values)))
+	var sum float64
+	for _, v := range values[k : len(values)-k] {
+		sum += v
+	}
+	return sum / float64(len(values)-2*k)
+}
+
+// Integral 返回窗口内数据对时间的积分，即把每个桶的值视为在其时间跨度内保持不变的阶梯函数下的面积
+// 单位为"值×秒"，如在线人数的积分即为"人·秒"；与 Sum 不同，结果随桶的时间跨度缩放。过期的桶不参与计算
+func (w *TimeWindow) Integral() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	var area float64
+	for i, v := range w.buckets {
+		if w.populated(i) {
+			area += v * w.duration.Seconds()
+		}
+	}
+	return area
+}
+
+// Variance 返回窗口内有数据的桶的总体方差，分母为有数据的桶数 N 而不是 N-1
+// 空桶(参见 Count)不参与计算，有数据的桶少于两个时返回 0
+func (w *TimeWindow) Variance() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.variance()
+}
+
+// StdDev 返回窗口内有数据的桶的总体标准差，参见 Variance
+func (w *TimeWindow) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+func (w *TimeWindow) variance() float64 {
+	_, variance := w.moments(-1)
+	return variance
+}
+
+// moments 返回除位置 exclude 之外有数据的桶的平均值和总体方差，exclude 为 -1 时不排除任何桶
+// 有数据的桶少于两个时方差为 0
+func (w *TimeWindow) moments(exclude int) (mean, variance float64) {
+	var sum float64
+	var n int
+	for i, v := range w.buckets {
+		if i != exclude && w.populated(i) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	mean = sum / float64(n)
+	if n < 2 {
+		return mean, 0
+	}
+
+	var sq float64
+	for i, v := range w.buckets {
+		if i != exclude && w.populated(i) {
+			sq += (v - mean) * (v - mean)
+		}
+	}
+	return mean, sq / float64(n)
+}
+
+// ZScore 返回当前桶相对窗口内其余桶的标准分数，即 (当前桶 - 平均值) / 标准差
+// 平均值和标准差按 StdDev 的方式在除当前桶之外有数据的桶上计算；
+// 当前桶尚未写入或标准差为0(包括其余桶少于两个)时返回 0
+func (w *TimeWindow) ZScore() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	if !w.populated(w.cursor) {
+		return 0
+	}
+	mean, variance := w.moments(w.cursor)
+	if variance == 0 {
+		return 0
+	}
+	return (w.buckets[w.cursor] - mean) / math.Sqrt(variance)
+}
+
+// CurrentExceedsPercentile 返回当前桶的值是否大于窗口内其余桶的第 p 百分位数，参见 Percentile
+// 阈值随基线自动调整，不需要手动设定常量；当前桶尚未写入或其余有数据的桶少于两个时返回 false
+func (w *TimeWindow) CurrentExceedsPercentile(p float64) bool {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	if !w.populated(w.cursor) {
+		return false
+	}
+
+	rest := make([]float64, 0, w.size-1)
+	for i, v := range w.buckets {
+		if i != w.cursor && w.populated(i) {
+			rest = append(rest, v)
+		}
+	}
+	if len(rest) < 2 {
+		return false
+	}
+	sort.Float64s(rest)
+	return w.buckets[w.cursor] > percentile(rest, p)
+}
+
+// IsAnomaly 返回当前桶的标准分数的绝对值是否超过 threshold，参见 ZScore
+func (w *TimeWindow) IsAnomaly(threshold float64) bool {
+	return math.Abs(w.ZScore()) > threshold
+}
+
+// EMA 返回窗口内数据的指数移动平均值
+// 按从旧到新的顺序遍历有数据的桶，以最旧的值为初始值，之后依次计算
+// ema = alpha*value + (1-alpha)*ema，越新的值权重越大。
+// 空桶(参见 Count)被跳过；窗口为空时返回 0，alpha 不在 (0,1] 范围内时返回 NaN。
+func (w *TimeWindow) EMA(alpha float64) float64 {
+	if alpha <= 0 || alpha > 1 {
+		return math.NaN()
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	var ema float64
+	seeded := false
+	for age := w.size - 1; age >= 0; age-- {
+		idx := (w.cursor - age + w.size) % w.size
+		if !w.populated(idx) {
+			continue
+		}
+		if !seeded {
+			ema = w.buckets[idx]
+			seeded = true
+			continue
+		}
+		ema = alpha*w.buckets[idx] + (1-alpha)*ema
+	}
+	return ema
+}
+
+// WeightedAvg 返回按时间衰减加权的平均值
+// 年龄为 age 的桶距当前桶 age*duration，其权重为 exp(-ln2 * age*duration / halfLife)，
+// 即每经过 halfLife 权重减半，结果为 Σ(weight*value) / Σweight。
+// 空桶(参见 Count)不参与计算；窗口为空时返回 0，halfLife <= 0 时返回 NaN。
+func (w *TimeWindow) WeightedAvg(halfLife time.Duration) float64 {
+	if halfLife <= 0 {
+		return math.NaN()
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	var sum, weights float64
+	for age := 0; age < w.size; age++ {
+		idx := (w.cursor - age + w.size) % w.size
+		if !w.populated(idx) {
+			continue
+		}
+		offset := time.Duration(age) * w.duration
+		weight := math.Exp(-math.Ln2 * float64(offset) / float64(halfLife))
+		sum += weight * w.buckets[idx]
+		weights += weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// Rate 返回窗口内每秒的平均增量，即 Sum 除以整个窗口的时间跨度(秒)
+func (w *TimeWindow) Rate() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.sum / (time.Duration(w.size) * w.duration).Seconds()
+}
+
+// SmoothedRate 返回按时间衰减加权的每秒增量，类似系统负载的计算方式
+// 年龄为 age 的桶的权重与 WeightedAvg 相同，每经过 halfLife 减半，结果为 Σ(weight*value) / (Σweight * duration秒)。
+// 与 WeightedAvg 不同，空桶按0参与计算，表示该时间段内没有增量；增量恒定时与 Rate 相同，
+// 变化时比 Rate 更快地跟随最近的桶，又比单个桶平滑。halfLife <= 0 时返回 NaN。
+func (w *TimeWindow) SmoothedRate(halfLife time.Duration) float64 {
+	if halfLife <= 0 {
+		return math.NaN()
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	var sum, weights float64
+	for age := 0; age < w.size; age++ {
+		idx := (w.cursor - age + w.size) % w.size
+		offset := time.Duration(age) * w.duration
+		weight := math.Exp(-math.Ln2 * float64(offset) / float64(halfLife))
+		sum += weight * w.buckets[idx]
+		weights += weight
+	}
+	return sum / (weights * w.duration.Seconds())
+}
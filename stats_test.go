@@ -0,0 +1,383 @@
+package hstat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// newFilledWindow returns a window whose buckets hold values oldest→newest.
+// Zero values are left unwritten so they act as gaps.
+func newFilledWindow(clock *fakeClock, values ...float64) *TimeWindow {
+	w := NewTimeWindow(len(values), time.Second, WithClock(clock.Now))
+	for i, v := range values {
+		if i > 0 {
+			clock.Add(time.Second)
+		}
+		if v != 0 {
+			w.Append(v)
+		}
+	}
+	return w
+}
+
+func TestTimeWindow_IsMonotonic(t *testing.T) {
+	cases := []struct {
+		name       string
+		values     []float64
+		inc, dec   bool
+		wantStrict bool
+	}{
+		{"strictly increasing", []float64{1, 2, 3, 4}, true, false, true},
+		{"non-decreasing", []float64{1, 2, 2, 4}, true, false, false},
+		{"strictly decreasing", []float64{4, 3, 2, 1}, false, true, true},
+		{"constant", []float64{2, 2, 2, 2}, true, true, false},
+		{"mixed", []float64{1, 3, 2, 4}, false, false, false},
+		{"gaps ignored", []float64{1, 0, 2, 0, 3}, true, false, true},
+		{"single point", []float64{0, 0, 5, 0}, false, false, false},
+	}
+
+	for _, c := range cases {
+		w := newFilledWindow(newFakeClock(), c.values...)
+		inc, dec, strict := w.IsMonotonic()
+		if inc != c.inc || dec != c.dec || strict != c.wantStrict {
+			t.Errorf("%s: expected (%v, %v, %v), got (%v, %v, %v)",
+				c.name, c.inc, c.dec, c.wantStrict, inc, dec, strict)
+		}
+	}
+}
+
+func TestTimeWindow_MinMax(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 3, 0, -2, 7, 0)
+
+	if v := w.Min(); v != -2 {
+		t.Errorf("Expected min -2, got %f", v)
+	}
+	if v := w.Max(); v != 7 {
+		t.Errorf("Expected max 7, got %f", v)
+	}
+
+	// Empty buckets are skipped, so an all-positive window never reports 0
+	w = newFilledWindow(newFakeClock(), 0, 4, 0, 5)
+	if lo, hi := w.MinMax(); lo != 4 || hi != 5 {
+		t.Errorf("Expected (4, 5), got (%f, %f)", lo, hi)
+	}
+
+	w = NewTimeWindow(5, time.Second)
+	if lo, hi := w.MinMax(); lo != 0 || hi != 0 {
+		t.Errorf("Expected (0, 0) for empty window, got (%f, %f)", lo, hi)
+	}
+}
+
+func TestTimeWindow_Percentile(t *testing.T) {
+	// Empty buckets are excluded, leaving 10, 20, 30, 40, 50
+	w := newFilledWindow(newFakeClock(), 50, 0, 10, 40, 0, 20, 30)
+
+	cases := []struct {
+		p, want float64
+	}{
+		{0, 10},
+		{50, 30},
+		{90, 46},
+		{100, 50},
+		{-5, 10},
+		{150, 50},
+	}
+	for _, c := range cases {
+		if got := w.Percentile(c.p); got != c.want {
+			t.Errorf("Percentile(%v): expected %f, got %f", c.p, c.want, got)
+		}
+	}
+
+	got := w.Percentiles(50, 0, 100)
+	if got[0] != 30 || got[1] != 10 || got[2] != 50 {
+		t.Errorf("Expected [30 10 50], got %v", got)
+	}
+
+	if p := NewTimeWindow(5, time.Second).Percentile(99); p != 0 {
+		t.Errorf("Expected 0 for empty window, got %f", p)
+	}
+
+	if p := w.Percentile(math.NaN()); !math.IsNaN(p) {
+		t.Errorf("Expected NaN for a NaN percentile, got %f", p)
+	}
+}
+
+func TestTimeWindow_Variance(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 2, 4, 0, 4, 4, 5, 5, 7, 9)
+
+	if v := w.Variance(); v != 4 {
+		t.Errorf("Expected variance 4, got %f", v)
+	}
+	if s := w.StdDev(); s != 2 {
+		t.Errorf("Expected stddev 2, got %f", s)
+	}
+
+	w = newFilledWindow(newFakeClock(), 0, 3, 0)
+	if v := w.Variance(); v != 0 {
+		t.Errorf("Expected variance 0 for a single value, got %f", v)
+	}
+}
+
+func TestTimeWindow_EMA(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 10, 0, 20, 40)
+
+	// Seeded with 10, then 0.5*20 + 0.5*10 = 15, then 0.5*40 + 0.5*15 = 27.5
+	if ema := w.EMA(0.5); ema != 27.5 {
+		t.Errorf("Expected EMA 27.5, got %f", ema)
+	}
+
+	// alpha == 1 tracks the newest value only
+	if ema := w.EMA(1); ema != 40 {
+		t.Errorf("Expected EMA 40, got %f", ema)
+	}
+
+	if ema := w.EMA(0); !math.IsNaN(ema) {
+		t.Errorf("Expected NaN for alpha 0, got %f", ema)
+	}
+
+	if ema := NewTimeWindow(3, time.Second).EMA(0.5); ema != 0 {
+		t.Errorf("Expected 0 for empty window, got %f", ema)
+	}
+}
+
+func TestTimeWindow_WeightedAvg(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 40, 0, 10)
+
+	// With a 1s half-life the weights are 1 for age 0 and 0.25 for age 2,
+	// so (1*10 + 0.25*40) / 1.25 = 16; the gap at age 1 is skipped
+	if avg := w.WeightedAvg(time.Second); math.Abs(avg-16) > 1e-9 {
+		t.Errorf("Expected weighted avg 16, got %f", avg)
+	}
+
+	// A very long half-life approaches the flat average
+	if avg := w.WeightedAvg(1000 * time.Hour); math.Abs(avg-w.Avg()) > 1e-3 {
+		t.Errorf("Expected weighted avg close to %f, got %f", w.Avg(), avg)
+	}
+
+	if avg := w.WeightedAvg(0); !math.IsNaN(avg) {
+		t.Errorf("Expected NaN for zero half-life, got %f", avg)
+	}
+
+	if avg := NewTimeWindow(3, time.Second).WeightedAvg(time.Second); avg != 0 {
+		t.Errorf("Expected 0 for empty window, got %f", avg)
+	}
+}
+
+func TestTimeWindow_TrimmedMean(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 3, 1000, 1, 0, 2, 4, -500, 5, 6, 3)
+
+	// Nine values; a 0.2 fraction drops one from each end (-500 and 1000)
+	if mean := w.TrimmedMean(0.2); mean != 24.0/7 {
+		t.Errorf("Expected trimmed mean %f, got %f", 24.0/7, mean)
+	}
+
+	if mean := w.TrimmedMean(0); mean != w.Avg() {
+		t.Errorf("Expected plain mean %f, got %f", w.Avg(), mean)
+	}
+
+	for _, fraction := range []float64{-0.1, 0.5, math.NaN()} {
+		if mean := w.TrimmedMean(fraction); !math.IsNaN(mean) {
+			t.Errorf("Expected NaN for fraction %v, got %f", fraction, mean)
+		}
+	}
+
+	if mean := NewTimeWindow(3, time.Second).TrimmedMean(0.1); mean != 0 {
+		t.Errorf("Expected 0 for empty window, got %f", mean)
+	}
+}
+
+func TestTimeWindow_Median(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"empty", []float64{0, 0}, 0},
+		{"odd", []float64{5, 1, 0, 9}, 5},
+		{"even", []float64{4, 1, 0, 10, 2}, 3},
+		{"single", []float64{0, -3}, -3},
+	}
+	for _, c := range cases {
+		w := newFilledWindow(newFakeClock(), c.values...)
+		if got := w.Median(); got != c.want {
+			t.Errorf("%s: expected median %f, got %f", c.name, c.want, got)
+		}
+	}
+}
+
+func TestTimeWindow_ValueHistogram(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 2, 0, 4, 9, 10)
+
+	bins := w.ValueHistogram(3)
+	want := []HistBin{{1, 4, 2}, {4, 7, 1}, {7, 10, 2}}
+	if len(bins) != len(want) {
+		t.Fatalf("Expected %d bins, got %d", len(want), len(bins))
+	}
+	for i := range want {
+		if bins[i] != want[i] {
+			t.Errorf("Bin %d: expected %+v, got %+v", i, want[i], bins[i])
+		}
+	}
+
+	// Identical values get a unit-wide range around them
+	w = newFilledWindow(newFakeClock(), 3, 3)
+	if bins := w.ValueHistogram(1); len(bins) != 1 || bins[0] != (HistBin{2.5, 3.5, 2}) {
+		t.Errorf("Expected a single bin [2.5, 3.5] with 2 values, got %+v", bins)
+	}
+
+	if bins := NewTimeWindow(3, time.Second).ValueHistogram(4); bins == nil || len(bins) != 0 {
+		t.Errorf("Expected an empty slice for an empty window, got %v", bins)
+	}
+	if bins := w.ValueHistogram(0); len(bins) != 0 {
+		t.Errorf("Expected an empty slice for zero bins, got %v", bins)
+	}
+}
+
+func TestTimeWindow_ValueHistogramExtremes(t *testing.T) {
+	// The range overflows float64 and non-finite values are skipped
+	w := newFilledWindow(newFakeClock(), -1e308, math.NaN(), 1e308, math.Inf(1), 1)
+
+	for _, n := range []int{1, 2, 5} {
+		bins := w.ValueHistogram(n)
+		if len(bins) != n || bins[0].Lo != -1e308 || bins[n-1].Hi != 1e308 {
+			t.Fatalf("Expected %d bins spanning [-1e308, 1e308], got %+v", n, bins)
+		}
+		total := 0
+		for _, b := range bins {
+			if math.IsInf(b.Lo, 0) || math.IsInf(b.Hi, 0) {
+				t.Errorf("Expected finite edges, got %+v", b)
+			}
+			total += b.Count
+		}
+		if total != 3 {
+			t.Errorf("Expected 3 finite values across %d bins, got %d", n, total)
+		}
+	}
+
+	if bins := w.ValueHistogram(2); bins[0].Count != 1 || bins[1].Count != 2 {
+		t.Errorf("Expected counts [1 2], got %+v", bins)
+	}
+
+	if bins := newFilledWindow(newFakeClock(), math.NaN()).ValueHistogram(3); len(bins) != 0 {
+		t.Errorf("Expected an empty slice when no value is finite, got %+v", bins)
+	}
+}
+
+func TestTimeWindow_Integral(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, 30*time.Second, WithClock(clock.Now))
+
+	// 10 users for 30s, then 4 users for 30s: 300 + 120 user-seconds
+	w.Inc(10)
+	clock.Add(30 * time.Second)
+	w.Inc(4)
+
+	if got := w.Integral(); got != 420 {
+		t.Errorf("Expected integral 420, got %f", got)
+	}
+
+	// Expired buckets drop out of the area
+	clock.Add(90 * time.Second)
+	if got := w.Integral(); got != 120 {
+		t.Errorf("Expected integral 120 after expiry, got %f", got)
+	}
+}
+
+func TestTimeWindow_ZScore(t *testing.T) {
+	// The rest of the window has mean 5 and standard deviation 1
+	w := newFilledWindow(newFakeClock(), 4, 6, 4, 6, 9)
+	if z := w.ZScore(); z != 4 {
+		t.Errorf("Expected z-score 4, got %f", z)
+	}
+	if !w.IsAnomaly(3) {
+		t.Error("Expected an anomaly above threshold 3")
+	}
+	if w.IsAnomaly(4) {
+		t.Error("Expected no anomaly at threshold 4")
+	}
+
+	w = newFilledWindow(newFakeClock(), 4, 6, 4, 6, 2)
+	if z := w.ZScore(); z != -3 {
+		t.Errorf("Expected z-score -3, got %f", z)
+	}
+
+	// A flat history has no spread to compare against
+	w = newFilledWindow(newFakeClock(), 5, 5, 5, 100)
+	if z := w.ZScore(); z != 0 || w.IsAnomaly(1) {
+		t.Errorf("Expected z-score 0 for zero deviation, got %f", z)
+	}
+
+	// An unwritten current bucket is not an anomaly
+	w = newFilledWindow(newFakeClock(), 4, 6, 0)
+	if z := w.ZScore(); z != 0 {
+		t.Errorf("Expected z-score 0 for an empty current bucket, got %f", z)
+	}
+}
+
+func TestTimeWindow_SmoothedRate(t *testing.T) {
+	// A constant 10 per 2s bucket is 5/s under either measure
+	w := NewTimeWindow(4, 2*time.Second, WithClock(newFakeClock().Now))
+	w.ResetAll(10)
+	if r := w.Rate(); r != 5 {
+		t.Errorf("Expected rate 5, got %f", r)
+	}
+	if r := w.SmoothedRate(time.Second); math.Abs(r-5) > 1e-9 {
+		t.Errorf("Expected smoothed rate 5, got %f", r)
+	}
+
+	// A noisy step from 0/s to about 10/s
+	clock := newFakeClock()
+	w = NewTimeWindow(20, time.Second, WithClock(clock.Now))
+	var prevRaw, prevSmoothed float64
+	var rawJump, smoothedJump float64
+	for i := 0; i < 10; i++ {
+		w.Inc(float64(10 + 4*(i%2*2-1)))
+		raw, _ := w.GetLatestValue()
+		smoothed := w.SmoothedRate(2 * time.Second)
+		if i > 5 {
+			rawJump = max(rawJump, math.Abs(raw-prevRaw))
+			smoothedJump = max(smoothedJump, math.Abs(smoothed-prevSmoothed))
+		}
+		prevRaw, prevSmoothed = raw, smoothed
+		clock.Add(time.Second)
+	}
+	clock.Add(-time.Second)
+
+	// Smoothing damps the tick-to-tick noise of the latest bucket
+	if smoothedJump >= rawJump/2 {
+		t.Errorf("Expected smoothed jumps below %f, got %f", rawJump/2, smoothedJump)
+	}
+	// ...while tracking the step much closer than the whole-window rate
+	if rate, smoothed := w.Rate(), w.SmoothedRate(2*time.Second); rate >= 6 || smoothed <= 8 {
+		t.Errorf("Expected rate below 6 and smoothed rate above 8, got %f and %f", rate, smoothed)
+	}
+
+	if r := w.SmoothedRate(0); !math.IsNaN(r) {
+		t.Errorf("Expected NaN for non-positive half-life, got %f", r)
+	}
+}
+
+func TestTimeWindow_CurrentExceedsPercentile(t *testing.T) {
+	// The rest of the window is 1..10, so p90 is 9.1
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 9.5}
+	w := newFilledWindow(newFakeClock(), values...)
+	if !w.CurrentExceedsPercentile(90) {
+		t.Error("Expected 9.5 to exceed p90 of the baseline")
+	}
+	if w.CurrentExceedsPercentile(95) {
+		t.Error("Expected 9.5 not to exceed p95 of the baseline")
+	}
+
+	// Too little baseline to judge
+	w = newFilledWindow(newFakeClock(), 0, 1, 100)
+	if w.CurrentExceedsPercentile(50) {
+		t.Error("Expected false with a single baseline bucket")
+	}
+
+	// An unwritten current bucket never exceeds
+	w = newFilledWindow(newFakeClock(), 1, 2, 3, 0)
+	if w.CurrentExceedsPercentile(0) {
+		t.Error("Expected false for an empty current bucket")
+	}
+}
@@ -0,0 +1,143 @@
+package hstat
+
+import (
+	"sync"
+	"time"
+)
+
+// statBucket 保存一个桶内所有观测值的和与观测次数
+type statBucket struct {
+	sum   float64
+	count int
+}
+
+// StatWindow 表示一个同时记录观测值之和与观测次数的时间窗口
+// 与 TimeWindow 只保存单个值不同，StatWindow 可以正确地计算跨桶的平均值
+type StatWindow struct {
+	mu         sync.RWMutex
+	buckets    []statBucket
+	size       int              // 窗口大小(桶的数量)
+	duration   time.Duration    // 每个桶的时间跨度
+	lastTime   time.Time        // 上次更新时间
+	cursor     int              // 当前桶的位置
+	lastUpdate time.Time        // 最近一次数据更新时间
+	clock      func() time.Time // 时钟，为 nil 时使用 time.Now
+}
+
+// NewStatWindow 创建一个新的统计窗口
+// size: 窗口中桶的数量，< 1 时按1处理
+// duration: 每个桶的时间跨度
+// opts 与 NewTimeWindow 的选项相同，但只有 WithClock 生效，其他选项被忽略
+func NewStatWindow(size int, duration time.Duration, opts ...Option) *StatWindow {
+	size = max(size, 1)
+	w := &StatWindow{
+		buckets:  make([]statBucket, size),
+		size:     size,
+		duration: duration,
+		clock:    clockOption(opts),
+	}
+	w.lastTime = w.now()
+	return w
+}
+
+func (w *StatWindow) now() time.Time {
+	if w.clock != nil {
+		return w.clock()
+	}
+	return time.Now()
+}
+
+// Observe 在当前桶中记录一次观测值
+func (w *StatWindow) Observe(value float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.rotate(now)
+	w.lastUpdate = now
+
+	w.buckets[w.cursor].sum += value
+	w.buckets[w.cursor].count++
+}
+
+// rotate 根据时间推移调整窗口
+func (w *StatWindow) rotate(now time.Time) {
+	if w.duration <= 0 {
+		w.duration = defaultDuration
+	}
+	passed := int(now.Sub(w.lastTime) / w.duration)
+	if passed <= 0 {
+		return
+	}
+
+	w.cursor = advanceRing(w.buckets, w.cursor, passed)
+	w.lastTime = bucketStart(w.lastTime, now, w.duration, passed)
+}
+
+// Sum 计算窗口内所有观测值的和
+func (w *StatWindow) Sum() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	sum, _ := w.totals()
+	return sum
+}
+
+// Count 返回窗口内的观测次数
+func (w *StatWindow) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	_, count := w.totals()
+	return count
+}
+
+// WindowAvg 返回窗口内所有观测值的平均值(总和/总次数)
+// 每个观测值的权重相同，而不是每个桶的权重相同
+func (w *StatWindow) WindowAvg() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	sum, count := w.totals()
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// BucketAvg 返回指定桶内观测值的平均值，age为0表示当前桶，1表示前一个桶，以此类推
+// age 超出范围或桶内没有观测值时返回 0
+func (w *StatWindow) BucketAvg(age int) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(w.now())
+	if age < 0 || age >= w.size {
+		return 0
+	}
+	b := w.buckets[(w.cursor-age+w.size)%w.size]
+	if b.count == 0 {
+		return 0
+	}
+	return b.sum / float64(b.count)
+}
+
+// LastUpdateTime 返回最近一次数据更新时间
+func (w *StatWindow) LastUpdateTime() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastUpdate
+}
+
+func (w *StatWindow) totals() (float64, int) {
+	var sum float64
+	var count int
+	for _, b := range w.buckets {
+		sum += b.sum
+		count += b.count
+	}
+	return sum, count
+}
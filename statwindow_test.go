@@ -0,0 +1,75 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatWindow_Basic(t *testing.T) {
+	clock := newFakeClock()
+	w := NewStatWindow(5, time.Second, WithClock(clock.Now))
+
+	w.Observe(10)
+	w.Observe(20)
+	clock.Add(time.Second)
+	w.Observe(60)
+
+	if sum := w.Sum(); sum != 90 {
+		t.Errorf("Expected sum 90, got %f", sum)
+	}
+	if count := w.Count(); count != 3 {
+		t.Errorf("Expected count 3, got %d", count)
+	}
+
+	// The window average weights every observation equally: 90/3
+	if avg := w.WindowAvg(); avg != 30 {
+		t.Errorf("Expected window average 30, got %f", avg)
+	}
+
+	if avg := w.BucketAvg(0); avg != 60 {
+		t.Errorf("Expected current bucket average 60, got %f", avg)
+	}
+	if avg := w.BucketAvg(1); avg != 15 {
+		t.Errorf("Expected previous bucket average 15, got %f", avg)
+	}
+	if avg := w.BucketAvg(5); avg != 0 {
+		t.Errorf("Expected 0 for out of range age, got %f", avg)
+	}
+}
+
+func TestStatWindow_Rotation(t *testing.T) {
+	clock := newFakeClock()
+	w := NewStatWindow(3, time.Second, WithClock(clock.Now))
+
+	w.Observe(5)
+	clock.Add(3 * time.Second)
+
+	if count := w.Count(); count != 0 {
+		t.Errorf("Expected count 0 after expiry, got %d", count)
+	}
+	if avg := w.WindowAvg(); avg != 0 {
+		t.Errorf("Expected average 0 after expiry, got %f", avg)
+	}
+}
+
+func TestStatWindow_InvalidSize(t *testing.T) {
+	// Like NewTimeWindow, a non-positive size is clamped to one bucket
+	w := NewStatWindow(0, time.Second)
+	w.Observe(4)
+
+	if sum := w.Sum(); sum != 4 {
+		t.Errorf("Expected sum 4, got %f", sum)
+	}
+}
+
+func TestStatWindow_NegativeDuration(t *testing.T) {
+	clock := newFakeClock()
+	w := NewStatWindow(3, -time.Millisecond, WithClock(clock.Now))
+
+	// A non-positive duration falls back to the default instead of never rotating
+	w.Observe(4)
+	clock.Add(time.Hour)
+	if count := w.Count(); count != 0 {
+		t.Errorf("Expected the observation to expire, got count %d", count)
+	}
+}
@@ -0,0 +1,35 @@
+package hstat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StreamJSON 每隔 interval 将 AggregateSnapshot 编码为 JSON 并交给 f，直到 ctx 被取消或 f 返回错误
+// 只负责推送循环，不关心传输方式，f 中可以写入 SSE、WebSocket 等连接；第一次推送在经过 interval 之后。
+// ctx 被取消时返回 ctx.Err()，f 返回错误时原样返回该错误；interval <= 0 时立即返回错误
+func (w *TimeWindow) StreamJSON(ctx context.Context, interval time.Duration, f func([]byte) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid stream interval %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			data, err := json.Marshal(w.AggregateSnapshot())
+			if err != nil {
+				return err
+			}
+			if err := f(data); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package hstat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_StreamJSON(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+	w.Inc(3.0)
+
+	// Stop after three pushes by returning an error from the callback
+	errStop := errors.New("stop")
+	var pushes []AggSnapshot
+	err := w.StreamJSON(context.Background(), time.Millisecond, func(data []byte) error {
+		var s AggSnapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		pushes = append(pushes, s)
+		if len(pushes) == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if len(pushes) != 3 || pushes[0].Sum != 3.0 {
+		t.Errorf("Expected 3 snapshots with sum 3, got %+v", pushes)
+	}
+}
+
+func TestTimeWindow_StreamJSONCancel(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := w.StreamJSON(ctx, time.Millisecond, func([]byte) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if err := w.StreamJSON(context.Background(), 0, nil); err == nil {
+		t.Error("Expected error for non-positive interval")
+	}
+}
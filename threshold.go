@@ -0,0 +1,62 @@
+package hstat
+
+// Edge 表示阈值告警触发的方向
+type Edge int
+
+const (
+	// Rising 在 Sum 从不高于阈值变为高于阈值时触发
+	Rising Edge = iota
+	// Falling 在 Sum 从不低于阈值变为低于阈值时触发
+	Falling
+)
+
+// threshold 保存阈值告警的配置和状态
+type threshold struct {
+	value float64
+	edge  Edge
+	ch    chan<- float64
+	above bool // 上次检查时 Sum 是否高于阈值
+	below bool // 上次检查时 Sum 是否低于阈值
+}
+
+// SetThreshold 设置 Sum 的阈值告警
+// 每次 Inc、Dec、Append、Reset、Add 及其 *At 变体和 Merge 更新数据后检查 Sum，
+// 只有在按 edge 方向越过阈值的那一次才把当前 Sum 发送到 ch，持续处于阈值之上(或之下)不会重复发送。
+// 旋转、Clear、ResetAll、Restore 等非写入操作改变 Sum 时只更新越过阈值的状态而不告警，使之后的写入能够再次触发。
+// 发送是非阻塞的，ch 已满时该次告警被丢弃。再次调用会替换之前的设置，ch 为 nil 时取消告警。
+func (w *TimeWindow) SetThreshold(value float64, edge Edge, ch chan<- float64) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if ch == nil {
+		w.threshold = nil
+		return
+	}
+	w.threshold = &threshold{
+		value: value,
+		edge:  edge,
+		ch:    ch,
+		above: w.sum > value,
+		below: w.sum < value,
+	}
+}
+
+// checkThreshold 检查 Sum 是否越过阈值，需要告警时返回 true，调用方需持有写锁
+func (w *TimeWindow) checkThreshold() bool {
+	t := w.threshold
+	if t == nil {
+		return false
+	}
+
+	above, below := w.sum > t.value, w.sum < t.value
+	crossed := (t.edge == Rising && above && !t.above) || (t.edge == Falling && below && !t.below)
+	t.above, t.below = above, below
+	return crossed
+}
+
+// syncThreshold 按当前 Sum 更新阈值的状态但不告警，在非写入操作改变 Sum 之后调用，调用方需持有写锁
+func (w *TimeWindow) syncThreshold() {
+	if t := w.threshold; t != nil {
+		t.above, t.below = w.sum > t.value, w.sum < t.value
+	}
+}
@@ -0,0 +1,95 @@
+package hstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_ThresholdRising(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+	ch := make(chan float64, 10)
+	w.SetThreshold(10, Rising, ch)
+
+	w.Inc(5)
+	w.Inc(6)  // crosses to 11
+	w.Inc(1)  // still above, no alert
+	w.Dec(5)  // drops to 7
+	w.Inc(10) // crosses again to 17
+
+	got := drain(ch)
+	if len(got) != 2 || got[0] != 11 || got[1] != 17 {
+		t.Errorf("Expected alerts [11 17], got %v", got)
+	}
+}
+
+func TestTimeWindow_ThresholdFalling(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+	w.Inc(20)
+
+	ch := make(chan float64, 10)
+	w.SetThreshold(10, Falling, ch)
+
+	w.Dec(5) // 15, still above
+	w.Dec(8) // crosses to 7
+	w.Dec(1) // still below
+	w.Append(12)
+	w.Reset(3) // crosses to 3
+
+	got := drain(ch)
+	if len(got) != 2 || got[0] != 7 || got[1] != 3 {
+		t.Errorf("Expected alerts [7 3], got %v", got)
+	}
+}
+
+func TestTimeWindow_ThresholdAfterExpiry(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	ch := make(chan float64, 10)
+	w.SetThreshold(10, Rising, ch)
+
+	w.Inc(15)
+
+	// The data expires, so the next write crosses the threshold again
+	clock.Add(10 * time.Second)
+	w.Inc(15)
+
+	// Clear drops the sum as well
+	w.Clear()
+	w.Inc(15)
+
+	got := drain(ch)
+	if len(got) != 3 {
+		t.Errorf("Expected 3 alerts, got %v", got)
+	}
+}
+
+func TestTimeWindow_ThresholdNonBlocking(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+	ch := make(chan float64)
+	w.SetThreshold(1, Rising, ch)
+
+	// Nobody is receiving; the update must not block
+	done := make(chan struct{})
+	go func() {
+		w.Inc(5)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Inc blocked on a full alert channel")
+	}
+}
+
+func drain(ch chan float64) []float64 {
+	var values []float64
+	for {
+		select {
+		case v := <-ch:
+			values = append(values, v)
+		default:
+			return values
+		}
+	}
+}
@@ -1,54 +1,226 @@
 package hstat
 
 import (
+	"context"
 	"database/sql/driver"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"math"
+	"slices"
 	"sync"
 	"time"
 )
 
+// ErrTooOld 表示写入的时间早于窗口覆盖的范围，参见 IncAt 和 AppendAt
+var ErrTooOld = errors.New("hstat: time is older than the window")
+
+// defaultDuration 是 duration <= 0 时每个桶的时间跨度
+const defaultDuration = 5 * time.Minute
+
 // TimeWindow 表示一个基于时间的滑动窗口
 type TimeWindow struct {
 	mu         sync.RWMutex
-	buckets    []float64     // 改为单个float64值的切片
-	size       int           // 窗口大小(桶的数量)
-	duration   time.Duration // 每个桶的时间跨度
-	lastTime   time.Time     // 上次更新时间
-	cursor     int           // 当前桶的位置
-	lastUpdate time.Time     // 最近一次数据更新时间
+	buckets    []float64        // 改为单个float64值的切片
+	written    []bool           // 每个桶在当前生命周期内是否被写入过
+	size       int              // 窗口大小(桶的数量)
+	duration   time.Duration    // 每个桶的时间跨度
+	lastTime   time.Time        // 上次更新时间
+	cursor     int              // 当前桶的位置
+	lastUpdate time.Time        // 最近一次数据更新时间
+	createdAt  time.Time        // 创建时间，参见 ElapsedBuckets
+	clock      func() time.Time // 时钟，为 nil 时使用 time.Now
+	prefix     []float64        // 按存储顺序的前缀和，仅在启用 WithPrefixSums 时维护
+	counts     []int            // 每个桶的写入次数，仅在启用 WithSampleCounts 时维护
+	weights    []float64        // 每个桶的总权重，第一次调用 IncWeighted 时分配
+	sum        float64          // 所有桶的和，随写入和旋转增量维护
+	cumulative float64          // 自创建以来所有写入带来的变化量之和，不随旋转减少
+	aggMode    AggMode          // Add 使用的聚合方式
+	paused     bool             // 是否已暂停，参见 Pause
+	pausedAt   time.Time        // 暂停的时间
+
+	onRotate     func(int)     // 旋转回调，参见 OnRotate
+	onUpdate     func(float64) // 更新回调，参见 OnUpdate
+	rotated      int           // 持锁期间旋转清空的桶数量，释放锁时通知
+	updated      bool          // 持锁期间是否有数据更新，释放锁时通知
+	updatedValue float64       // 最近一次更新后当前桶的值
+	threshold    *threshold    // 阈值告警，参见 SetThreshold
+	alert        bool          // 持锁期间是否越过阈值，释放锁时发送
+
+	janitorStop context.CancelFunc // 停止后台旋转，参见 StartJanitor
+	janitorDone chan struct{}      // 后台旋转的 goroutine 退出时关闭
+	sumChanged  chan struct{}      // 有 WaitForSum 等待时，和偏离 sumSeen 后关闭
+	sumSeen     float64            // 等待者最近一次检查时的和
 }
 
 // NewTimeWindow 创建一个新的时间窗口
 // size: 窗口中桶的数量
 // duration: 每个桶的时间跨度
 // chartHeight: 图表最大高度（如果 <= 0，则使用默认值20）
-func NewTimeWindow(size int, duration time.Duration) *TimeWindow {
-	return &TimeWindow{
+// opts: 可选配置，如 WithClock
+// size < 1 时按1处理，duration <= 0 时在第一次旋转时使用默认值5分钟；需要显式报错时使用 NewTimeWindowChecked
+func NewTimeWindow(size int, duration time.Duration, opts ...Option) *TimeWindow {
+	size = max(size, 1)
+	w := &TimeWindow{
 		buckets:  make([]float64, size),
+		written:  make([]bool, size),
 		size:     size,
 		duration: duration,
-		lastTime: time.Now(),
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.lastTime = w.now()
+	w.createdAt = w.lastTime
+	return w
+}
+
+// NewTimeWindowAt 与 NewTimeWindow 相同，但当前桶从 start 开始，而不是从时钟的当前时间开始
+// 创建时间同样为 start，便于在测试中以已知的起点精确推进窗口；通常与 WithClock 一起使用
+func NewTimeWindowAt(size int, duration time.Duration, start time.Time, opts ...Option) *TimeWindow {
+	w := NewTimeWindow(size, duration, opts...)
+	w.lastTime = start
+	w.createdAt = start
+	return w
+}
+
+// NewTimeWindowChecked 与 NewTimeWindow 相同，但在 size <= 0 或 duration <= 0 时返回错误
+func NewTimeWindowChecked(size int, duration time.Duration, opts ...Option) (*TimeWindow, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", size)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("invalid bucket duration %s", duration)
+	}
+	return NewTimeWindow(size, duration, opts...), nil
+}
+
+// now 返回窗口时钟的当前时间
+func (w *TimeWindow) now() time.Time {
+	if w.clock != nil {
+		return w.clock()
+	}
+	return time.Now()
 }
 
 // Append 添加一个值到当前时间窗口
 func (w *TimeWindow) Append(value float64) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
+
+	w.appendAt(w.now(), value)
+}
+
+// AppendAt 将值写入时间 t 所在的桶，用于按事件时间回放历史数据
+// 晚于当前桶的时间会使窗口旋转到该时间；落在窗口内较早的桶时直接覆盖该桶，不回退窗口；
+// 早于窗口覆盖范围的时间返回 ErrTooOld，窗口保持不变。
+func (w *TimeWindow) AppendAt(t time.Time, value float64) error {
+	w.mu.Lock()
+	defer w.unlock()
+
+	idx, err := w.bucketAt(t)
+	if err != nil {
+		return err
+	}
+	w.lastUpdate = laterTime(w.lastUpdate, t)
+	w.setBucket(idx, value)
+	w.countSamples(idx, 1)
+	w.markUpdated()
+	return nil
+}
+
+// bucketAt 返回时间 t 所在桶的位置，t 晚于当前桶时先将窗口旋转到 t
+// 年龄为 k 的桶覆盖 [lastTime-k*duration, lastTime-(k-1)*duration)
+func (w *TimeWindow) bucketAt(t time.Time) (int, error) {
+	w.rotate(t)
+	if !t.Before(w.lastTime) {
+		return w.cursor, nil
+	}
+
+	// 先与窗口的起始时间比较，避免 t 过早(如零值时间)时 Sub 饱和、年龄计算溢出为负数
+	start := w.lastTime.Add(-time.Duration(w.size-1) * w.duration)
+	tooOld := t.Before(start)
+	age := 0
+	if !tooOld {
+		age = int((w.lastTime.Sub(t) + w.duration - 1) / w.duration)
+	}
+	if tooOld || age < 0 || age >= w.size {
+		return 0, fmt.Errorf("%w: %s is before the window starting at %s", ErrTooOld,
+			t.Format(time.RFC3339Nano), start.Format(time.RFC3339Nano))
+	}
+	return (w.cursor - age + w.size) % w.size, nil
+}
 
-	now := time.Now()
+func (w *TimeWindow) appendAt(now time.Time, value float64) {
 	w.rotate(now)
+	w.lastUpdate = laterTime(w.lastUpdate, now)
 
 	// 直接设置当前桶的值
-	w.buckets[w.cursor] = value
+	w.setBucket(w.cursor, value)
+	w.countSamples(w.cursor, 1)
+	w.markUpdated()
+}
+
+// setBucket 设置指定位置桶的值并将其标记为已写入，同时维护依赖桶值的缓存
+// 所有对单个桶的写入都应通过该方法
+func (w *TimeWindow) setBucket(idx int, value float64) {
+	w.sum += value - w.buckets[idx]
+	w.cumulative += value - w.buckets[idx]
+	w.buckets[idx] = value
+	w.written[idx] = true
+	if w.prefix != nil {
+		w.updatePrefix(idx)
+	}
+}
+
+// clearBucket 清空指定位置的桶，前缀和由调用方在清空结束后统一重建
+func (w *TimeWindow) clearBucket(idx int) {
+	w.sum -= w.buckets[idx]
+	w.buckets[idx] = 0
+	w.written[idx] = false
+	if w.counts != nil {
+		w.counts[idx] = 0
+	}
+	if w.weights != nil {
+		w.weights[idx] = 0
+	}
+}
+
+// inferWritten 从不含写入标记的数据还原桶之后，以非零值推断桶是否被写入过
+func (w *TimeWindow) inferWritten() {
+	w.written = make([]bool, len(w.buckets))
+	for i, v := range w.buckets {
+		w.written[i] = v != 0
+	}
+}
+
+// recompute 在桶被整体替换后重建所有依赖桶值的缓存
+func (w *TimeWindow) recompute() {
+	if w.counts != nil && len(w.counts) != w.size {
+		w.counts = make([]int, w.size)
+	}
+	if w.weights != nil && len(w.weights) != w.size {
+		w.weights = make([]float64, w.size)
+	}
+	w.sum = 0
+	for _, v := range w.buckets {
+		w.sum += v
+	}
+	if w.prefix != nil {
+		if len(w.prefix) != w.size+1 {
+			w.prefix = make([]float64, w.size+1)
+		}
+		w.updatePrefix(0)
+	}
+	w.syncThreshold()
 }
 
 // rotate 根据时间推移调整窗口
 func (w *TimeWindow) rotate(now time.Time) {
-	if w.duration == 0 {
-		w.duration = 5 * time.Minute
+	if w.duration <= 0 {
+		w.duration = defaultDuration
+	}
+	if w.paused {
+		return
 	}
 	passed := int(now.Sub(w.lastTime) / w.duration)
 	if passed <= 0 {
@@ -58,191 +230,290 @@ func (w *TimeWindow) rotate(now time.Time) {
 	// 如果经过的时间超过窗口大小，清空所有桶
 	if passed >= w.size {
 		for i := range w.buckets {
-			w.buckets[i] = 0
+			w.clearBucket(i)
 		}
 		w.cursor = 0
+		w.sum = 0
+		w.rotated += w.size
 	} else {
 		// 清空过期的桶
 		for i := 0; i < passed; i++ {
 			w.cursor = (w.cursor + 1) % w.size
-			w.buckets[w.cursor] = 0
+			w.clearBucket(w.cursor)
 		}
+		w.rotated += passed
 	}
+	if w.prefix != nil {
+		w.updatePrefix(0)
+	}
+	w.syncThreshold()
 
-	w.lastTime = now
+	w.lastTime = bucketStart(w.lastTime, now, w.duration, passed)
+}
+
+// rlockRotated 将窗口旋转到 now 并持有读锁返回，调用方负责 RUnlock
+// 不需要旋转时(最常见的情况)只加读锁，不阻塞其他读取；需要旋转时先在写锁下旋转，再重新加读锁，
+// 两次加锁之间窗口可能被其他调用修改，但不会再需要旋转到 now 之前
+func (w *TimeWindow) rlockRotated(now time.Time) {
+	w.mu.RLock()
+	if w.duration > 0 && w.expired(now) == 0 {
+		return
+	}
+	w.mu.RUnlock()
+
+	w.mu.Lock()
+	w.rotate(now)
+	w.unlock()
+	w.mu.RLock()
 }
 
-// Sum 计算窗口内所有值的和
+// bucketStart 返回旋转 passed 个桶之后当前桶的起始时间
+// 按整数个桶推进而不是直接取 now，保留不足一个桶的时间，使桶的边界不随调用时间漂移；
+// 时间差超出 time.Duration 的范围(如从零值时间开始)时对齐到 now
+func bucketStart(last, now time.Time, duration time.Duration, passed int) time.Time {
+	next := last.Add(time.Duration(passed) * duration)
+	if now.Sub(next) >= duration {
+		return now
+	}
+	return next
+}
+
+// Sum 返回窗口内所有值的和，和随写入增量维护，复杂度为 O(1)
 func (w *TimeWindow) Sum() float64 {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	var sum float64
+	return w.sum
+}
+
+// SumKahan 使用 Neumaier 补偿求和重新计算所有桶的和，与 Sum 一样不旋转窗口
+// Sum 在每次写入和旋转时增量维护，复杂度为 O(1)，但长时间运行、大量细小增量或数量级相差悬殊的值会累积浮点误差；
+// SumKahan 每次遍历所有桶，复杂度为 O(size)，结果不受增量维护的误差影响，适合计费等对精度敏感的场景。
+// 补偿只作用于桶之间的求和，同一个桶内多次 Inc 的累加误差无法恢复
+func (w *TimeWindow) SumKahan() float64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var sum, c float64
 	for _, v := range w.buckets {
-		sum += v
+		t := sum + v
+		if math.Abs(sum) >= math.Abs(v) {
+			c += (sum - t) + v
+		} else {
+			c += (v - t) + sum
+		}
+		sum = t
 	}
-	return sum
+	return sum + c
 }
 
-// Count 返回窗口内的非零值的数量
+// Count 返回窗口内被写入过的桶的数量
+// 被写入过的桶即使值为0或负数也会被计入，从未写入或已过期的桶不计入
 func (w *TimeWindow) Count() int {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
+	return w.count()
+}
+
+func (w *TimeWindow) count() int {
 	var count int
-	for _, v := range w.buckets {
-		if v != 0 {
+	for i := range w.buckets {
+		if w.populated(i) {
 			count++
 		}
 	}
 	return count
 }
 
-// Avg 计算窗口内值的平均值
+// populated 判断指定位置的桶是否有数据，即在当前生命周期内是否被写入过
+func (w *TimeWindow) populated(idx int) bool {
+	return w.written[idx]
+}
+
+// Avg 计算窗口内值的平均值，分母为 Count
 func (w *TimeWindow) Avg() float64 {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	count := w.Count()
+	count := w.count()
 	if count == 0 {
 		return 0
 	}
-	return w.Sum() / float64(count)
+	return w.sum / float64(count)
 }
 
 // Inc 在当前时间窗口中累加值
 func (w *TimeWindow) Inc(delta float64) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
+
+	w.incAt(w.now(), delta)
+}
+
+// TryInc 在不阻塞的情况下尝试累加值，锁被其他调用持有时立即返回 false
+// 返回 false 时本次的值被丢弃，不会稍后补写，适合宁可丢失样本也不能等待锁的热路径
+func (w *TimeWindow) TryInc(delta float64) bool {
+	if !w.mu.TryLock() {
+		return false
+	}
+	defer w.unlock()
+
+	w.incAt(w.now(), delta)
+	return true
+}
+
+// IncAt 将 delta 累加到时间 t 所在的桶，用于按事件时间处理乱序到达的数据
+// 显式时间只对本次调用生效，优先于窗口时钟；窗口时间只会向前推进：
+// 晚于当前桶的时间会使窗口旋转到该时间，落在窗口内较早的桶时累加到该桶，不回退窗口。
+// 早于窗口覆盖范围的时间返回 ErrTooOld，该值被丢弃，窗口保持不变。
+// 回放数据把窗口推进到时钟之前的时间后，基于时钟的调用会一直写入当前桶，直到时钟追上。
+func (w *TimeWindow) IncAt(t time.Time, delta float64) error {
+	w.mu.Lock()
+	defer w.unlock()
 
-	now := time.Now()
+	idx, err := w.bucketAt(t)
+	if err != nil {
+		return err
+	}
+	w.lastUpdate = laterTime(w.lastUpdate, t)
+	w.setBucket(idx, w.buckets[idx]+delta)
+	w.countSamples(idx, 1)
+	w.markUpdated()
+	return nil
+}
+
+func (w *TimeWindow) incAt(now time.Time, delta float64) {
 	w.rotate(now)
-	w.lastUpdate = now
+	w.lastUpdate = laterTime(w.lastUpdate, now)
 
-	w.buckets[w.cursor] += delta
+	w.setBucket(w.cursor, w.buckets[w.cursor]+delta)
+	w.countSamples(w.cursor, 1)
+	w.markUpdated()
 }
 
 // Dec 在当前时间窗口中递减值
 func (w *TimeWindow) Dec(delta float64) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
 
-	now := time.Now()
-	w.rotate(now)
-	w.lastUpdate = now
-
-	w.buckets[w.cursor] -= delta
+	w.incAt(w.now(), -delta)
 }
 
-// Reset 重置当前桶的值为指定值
-func (w *TimeWindow) Reset(value float64) {
+// IncBatch 在一次加锁内将 deltas 依次累加到当前桶，用于批量刷新缓冲的样本
+// 窗口只在开始时旋转一次，调用期间不经过时间，因此所有值都落在同一个桶中；deltas 为空时不做任何操作
+func (w *TimeWindow) IncBatch(deltas []float64) {
+	if len(deltas) == 0 {
+		return
+	}
+
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
 
-	now := time.Now()
+	now := w.now()
 	w.rotate(now)
+	w.lastUpdate = laterTime(w.lastUpdate, now)
+
+	value := w.buckets[w.cursor]
+	for _, d := range deltas {
+		value += d
+	}
+	w.setBucket(w.cursor, value)
+	w.countSamples(w.cursor, len(deltas))
+	w.markUpdated()
+}
+
+// AppendBatch 在一次加锁内将 values 依次写入当前桶
+// 与 IncBatch 相同，所有值都落在同一个桶中，因此结果等同于只写入最后一个值；values 为空时不做任何操作
+func (w *TimeWindow) AppendBatch(values []float64) {
+	if len(values) == 0 {
+		return
+	}
 
-	w.buckets[w.cursor] = value
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.appendAt(w.now(), values[len(values)-1])
+	w.countSamples(w.cursor, len(values)-1)
 }
 
-// HistogramOption 用于配置直方图显示选项
-type HistogramOption struct {
-	Height int // 图表高度
+// AddAndGet 在当前桶中累加 delta 并返回累加后当前桶的值，整个过程只加一次写锁
+func (w *TimeWindow) AddAndGet(delta float64) float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.incAt(w.now(), delta)
+	return w.buckets[w.cursor]
 }
 
-// DefaultHistogramOption 返回默认的直方图配置
-func DefaultHistogramOption() *HistogramOption {
-	return &HistogramOption{
-		Height: 20, // 默认高度
-	}
+// DecAndGet 从当前桶中减去 delta 并返回减去后当前桶的值
+func (w *TimeWindow) DecAndGet(delta float64) float64 {
+	return w.AddAndGet(-delta)
 }
 
-// PrintHistogram 返回时间窗口内的数据分布情况（垂直柱状图）
-func (w *TimeWindow) PrintHistogram(opt *HistogramOption) string {
+// Advance 将窗口推进到指定时间，清空期间过期的桶，参见 IncAt
+func (w *TimeWindow) Advance(t time.Time) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
 
-	// 在显示之前先更新窗口状态
-	w.rotate(time.Now())
+	w.rotate(t)
+}
 
-	if opt == nil {
-		opt = DefaultHistogramOption()
-	}
+// Reset 重置当前桶的值为指定值
+func (w *TimeWindow) Reset(value float64) {
+	w.mu.Lock()
+	defer w.unlock()
 
-	var result strings.Builder
-	result.WriteString("\nTime Window Histogram:\n\n")
+	now := w.now()
+	w.rotate(now)
+	w.lastUpdate = laterTime(w.lastUpdate, now)
 
-	// 获取所有值和时间，注意顺序要从最新到最旧
-	values := make([]float64, w.size)
-	times := make([]int, w.size)
-	maxValue := 0.0
+	w.setBucket(w.cursor, value)
+	w.markUpdated()
+}
 
-	// 从当前游标位置向前收集数据
-	for i := 0; i < w.size; i++ {
-		// 计算实际索引，从当前游标向前遍历
-		idx := (w.cursor - i + w.size) % w.size
-		times[i] = -i * int(w.duration.Seconds())
-
-		if w.buckets[idx] > 0 {
-			value := w.buckets[idx]
-			values[i] = value
-			if value > maxValue {
-				maxValue = value
-			}
-		}
-	}
+// Clear 清空窗口内的所有桶，游标回到0，当前桶和最近更新时间都设为当前时间
+// 窗口大小、时间跨度和各项配置保持不变，之后可以继续正常写入
+func (w *TimeWindow) Clear() {
+	w.resetAll(0, false)
+}
 
-	height := opt.Height
-	if maxValue == 0 {
-		return "No data available\n"
-	}
+// ResetAll 将窗口内的所有桶都设为 value 并视为被写入过，游标回到0，当前桶和最近更新时间都设为当前时间
+func (w *TimeWindow) ResetAll(value float64) {
+	w.resetAll(value, true)
+}
 
-	// 打印柱状图（从上到下）
-	for h := height; h > 0; h-- {
-		threshold := maxValue * float64(h) / float64(height)
-		for i := 0; i < w.size; i++ {
-			if values[i] >= threshold {
-				result.WriteString("▇ ")
-			} else {
-				result.WriteString("  ")
-			}
-		}
-		result.WriteString("\n")
-	}
+func (w *TimeWindow) resetAll(value float64, written bool) {
+	w.mu.Lock()
+	defer w.unlock()
 
-	// 打印底部分隔线
-	for i := 0; i < w.size; i++ {
-		result.WriteString("──")
+	now := w.now()
+	for i := range w.buckets {
+		w.buckets[i] = value
+		w.written[i] = written
 	}
-	result.WriteString("\n")
-
-	// 打印数值
-	for i := 0; i < w.size; i++ {
-		if values[i] > 0 {
-			fmt.Fprintf(&result, "%-2.0f", values[i])
-		} else {
-			result.WriteString("  ")
-		}
+	clear(w.counts)
+	clear(w.weights)
+	w.cursor = 0
+	w.lastTime = now
+	w.lastUpdate = now
+	if w.paused {
+		w.pausedAt = now
 	}
-	result.WriteString("\n")
-
-	// 打印相对时间刻度
-	interval := 1
-	if w.size > 20 {
-		interval = w.size / 10
+	w.recompute()
+	if written {
+		w.markUpdated()
 	}
+}
 
-	// 打印时间刻度
-	for i := 0; i < w.size; i++ {
-		if i%interval == 0 {
-			fmt.Fprintf(&result, "%-2d", times[i])
-		} else {
-			result.WriteString("  ")
-		}
-	}
-	result.WriteString("s\n")
+// Total 返回窗口自创建以来的累计值，不受旋转影响
+// 每次写入按写入前后当前桶的差值计入：Inc 计入 delta，Dec 计入 -delta，
+// Append、Reset 和 Add 等覆盖写入计入新值与旧值之差；旋转、Clear 和 ResetAll 不改变累计值。
+func (w *TimeWindow) Total() float64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 
-	return result.String()
+	return w.cumulative
 }
 
 // LastUpdateTime 返回最近一次数据更新时间
@@ -252,108 +523,451 @@ func (w *TimeWindow) LastUpdateTime() time.Time {
 	return w.lastUpdate
 }
 
-// Value 实现 sql.Valuer 接口
-func (w *TimeWindow) Value() (driver.Value, error) {
-	if w == nil {
-		return nil, nil
+// Size 返回窗口中桶的数量
+func (w *TimeWindow) Size() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.size
+}
+
+// Duration 返回每个桶的时间跨度，创建时 duration <= 0 则返回默认值5分钟
+func (w *TimeWindow) Duration() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.bucketDuration()
+}
+
+// WindowSpan 返回整个窗口覆盖的时间跨度，即 Size()*Duration()
+func (w *TimeWindow) WindowSpan() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return time.Duration(w.size) * w.bucketDuration()
+}
+
+// ElapsedBuckets 返回自窗口创建以来经过的时间所覆盖的桶数量，即 min(size, 经过的时间/duration+1)
+// 刚创建的窗口为1，运行满一个窗口跨度后为 size；窗口未填满时可以用它代替 size 归一化速率。
+// 由原始数据或序列化数据还原的窗口从还原时开始计算
+func (w *TimeWindow) ElapsedBuckets() int {
+	now := w.now()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	elapsed := int(max(now.Sub(w.createdAt), 0) / w.bucketDuration())
+	return min(w.size, elapsed+1)
+}
+
+// bucketDuration 返回实际使用的桶时间跨度，调用方需持有锁
+func (w *TimeWindow) bucketDuration() time.Duration {
+	if w.duration <= 0 {
+		return defaultDuration
 	}
+	return w.duration
+}
 
+// String 返回窗口的单行摘要，用于调试日志，实现 fmt.Stringer
+// 只持有一次读锁且不旋转窗口，各字段来自同一时刻的状态
+func (w *TimeWindow) String() string {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	data := struct {
-		Buckets    []float64     `json:"buckets"`
-		Size       int           `json:"size"`
-		Duration   time.Duration `json:"duration"`
-		LastTime   time.Time     `json:"last_time"`
-		Cursor     int           `json:"cursor"`
-		LastUpdate time.Time     `json:"last_update"`
-	}{
-		Buckets:    w.buckets,
-		Size:       w.size,
-		Duration:   w.duration,
-		LastTime:   w.lastTime,
-		Cursor:     w.cursor,
-		LastUpdate: w.lastUpdate,
+	count := w.count()
+	var avg float64
+	if count > 0 {
+		avg = w.sum / float64(count)
+	}
+	lastUpdate := "never"
+	if !w.lastUpdate.IsZero() {
+		lastUpdate = w.lastUpdate.Format(time.RFC3339Nano)
 	}
+	return fmt.Sprintf("TimeWindow{size=%d duration=%s sum=%g count=%d avg=%g last_update=%s}",
+		w.size, w.bucketDuration(), w.sum, count, avg, lastUpdate)
+}
 
-	return json.Marshal(data)
+// StaleFor 返回最近一次数据更新距现在经过的时间
+// 从未更新过时返回 time.Duration 的最大值；最近更新时间晚于时钟(如回放数据)时返回0
+func (w *TimeWindow) StaleFor() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.staleFor()
 }
 
-// Scan 实现 sql.Scanner 接口
-func (w *TimeWindow) Scan(value interface{}) error {
-	if value == nil {
-		return nil
+// IsStale 判断最近一次数据更新距现在是否超过 maxAge，从未更新过时返回 true
+func (w *TimeWindow) IsStale(maxAge time.Duration) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.staleFor() > maxAge
+}
+
+func (w *TimeWindow) staleFor() time.Duration {
+	if w.lastUpdate.IsZero() {
+		return math.MaxInt64
 	}
+	return max(w.now().Sub(w.lastUpdate), 0)
+}
 
+// CurrentBucketElapsed 返回当前桶从开始到现在经过的时间，范围为 [0, duration)
+func (w *TimeWindow) CurrentBucketElapsed() time.Duration {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
+
+	return w.currentBucketElapsed(w.now())
+}
 
-	var data struct {
-		Buckets    []float64     `json:"buckets"`
-		Size       int           `json:"size"`
-		Duration   time.Duration `json:"duration"`
-		LastTime   time.Time     `json:"last_time"`
-		Cursor     int           `json:"cursor"`
-		LastUpdate time.Time     `json:"last_update"`
+// CurrentBucketFraction 返回当前桶已经过的时间占桶时间跨度的比例，范围为 [0,1]
+// 可用于将正在进行中的桶外推为完整区间，以得到稳定的实时速率
+func (w *TimeWindow) CurrentBucketFraction() float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	elapsed := w.currentBucketElapsed(w.now())
+	return float64(elapsed) / float64(w.duration)
+}
+
+func (w *TimeWindow) currentBucketElapsed(now time.Time) time.Duration {
+	w.rotate(now)
+	if w.paused {
+		now = w.pausedAt
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("expected []byte, got %T", value)
+	// 窗口被显式时间推进到时钟之前时，当前桶尚未开始
+	elapsed := now.Sub(w.lastTime)
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
+
+// Value 实现 sql.Valuer 接口
+func (w *TimeWindow) Value() (driver.Value, error) {
+	if w == nil {
+		return nil, nil
 	}
 
-	if err := json.Unmarshal(bytes, &data); err != nil {
-		data.Duration = 5 * time.Minute
+	return w.MarshalJSON()
+}
+
+// Scan 实现 sql.Scanner 接口
+func (w *TimeWindow) Scan(value interface{}) error {
+	if value == nil {
 		return nil
 	}
 
-	w.buckets = data.Buckets
-	w.size = data.Size
-	w.duration = data.Duration
-	w.lastTime = data.LastTime
-	w.cursor = data.Cursor
-	w.lastUpdate = data.LastUpdate
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte, got %T", value)
+	}
 
-	return nil
+	return w.UnmarshalJSON(bytes)
 }
 
 // TimeWindowData 表示时间窗口中的数据点
 type TimeWindowData struct {
-	Time   time.Time `json:"time"`   // 数据时间点
-	Values []float64 `json:"values"` // 该时间点的所有值
+	Time    time.Time `json:"time"`    // 数据时间点
+	Values  []float64 `json:"values"`  // 该时间点的所有值
+	Written bool      `json:"written"` // 该桶在当前生命周期内是否被写入过，用于区分空桶和值为0的桶
 }
 
-// GetData 返回时间窗口中的所有数据
+// GetData 返回时间窗口中的所有数据，按从新到旧的顺序排列，下标0为当前桶，下标即桶的年龄
+// 需要按时间顺序排列时使用 GetDataChronological
 func (w *TimeWindow) GetData() []TimeWindowData {
+	return w.GetDataInto(nil)
+}
+
+// GetDataChronological 与 GetData 相同，但按从旧到新的顺序排列，最后一个元素为当前桶，
+// 适合直接交给按时间轴绘图的图表库
+func (w *TimeWindow) GetDataChronological() []TimeWindowData {
+	data := w.GetData()
+	slices.Reverse(data)
+	return data
+}
+
+// GetDataInto 与 GetData 相同，但复用 buf 及其中每个元素的 Values，容量不足时才重新分配
+// 反复传入上一次的返回值时不再分配内存；返回的切片与 buf 共享存储
+func (w *TimeWindow) GetDataInto(buf []TimeWindowData) []TimeWindowData {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	defer w.unlock()
 
-	w.rotate(time.Now())
+	now := w.now()
+	w.rotate(now)
 
-	now := time.Now()
-	result := make([]TimeWindowData, w.size)
+	result := slices.Grow(buf[:0], w.size)[:w.size]
 
 	for i := 0; i < w.size; i++ {
 		idx := (w.cursor - i + w.size) % w.size
 		bucketTime := now.Add(-time.Duration(i) * w.duration)
 
 		// 将单个值包装在切片中保持兼容性
-		values := []float64{w.buckets[idx]}
+		values := append(result[i].Values[:0], w.buckets[idx])
 
 		result[i] = TimeWindowData{
-			Time:   bucketTime,
-			Values: values,
+			Time:    bucketTime,
+			Values:  values,
+			Written: w.written[idx],
 		}
 	}
 
 	return result
 }
 
-// GetLatestValue 返回最新的值
+// GetValuesInto 将桶的值按从新到旧的顺序写入 buf 并返回，下标即桶的年龄
+// 与 GetDataInto 相比不包含时间和写入标记，容量足够时不分配内存
+func (w *TimeWindow) GetValuesInto(buf []float64) []float64 {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	result := slices.Grow(buf[:0], w.size)[:w.size]
+	for i := range result {
+		result[i] = w.buckets[(w.cursor-i+w.size)%w.size]
+	}
+	return result
+}
+
+// GetRange 返回时间落在 [from, to] 内的桶，按从旧到新的顺序排列
+// 每个桶的时间与 GetData 一致；范围超出窗口的部分被忽略，完全在窗口之外时返回空切片
+func (w *TimeWindow) GetRange(from, to time.Time) []TimeWindowData {
+	w.mu.Lock()
+	defer w.unlock()
+
+	now := w.now()
+	w.rotate(now)
+
+	result := []TimeWindowData{}
+	for age := w.size - 1; age >= 0; age-- {
+		bucketTime := now.Add(-time.Duration(age) * w.duration)
+		if bucketTime.Before(from) || bucketTime.After(to) {
+			continue
+		}
+		idx := (w.cursor - age + w.size) % w.size
+		result = append(result, TimeWindowData{
+			Time:    bucketTime,
+			Values:  []float64{w.buckets[idx]},
+			Written: w.written[idx],
+		})
+	}
+	return result
+}
+
+// Order 表示遍历桶的顺序
+type Order int
+
+const (
+	// OldestFirst 从最旧的桶遍历到当前桶
+	OldestFirst Order = iota
+	// NewestFirst 从当前桶遍历到最旧的桶
+	NewestFirst
+)
+
+// ForEach 按 order 指定的顺序遍历窗口中的桶，fn 返回 false 时停止遍历
+// age 为桶的年龄(当前桶为0)，t 与 GetData 中的时间一致。遍历过程中不分配内存。
+// fn 在持有窗口锁时调用，不能在 fn 中调用该窗口的其他方法。
+func (w *TimeWindow) ForEach(order Order, fn func(age int, t time.Time, value float64) bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	now := w.now()
+	w.rotate(now)
+
+	for i := 0; i < w.size; i++ {
+		age := i
+		if order == OldestFirst {
+			age = w.size - 1 - i
+		}
+		idx := (w.cursor - age + w.size) % w.size
+		if !fn(age, now.Add(-time.Duration(age)*w.duration), w.buckets[idx]) {
+			return
+		}
+	}
+}
+
+// Reduce 以 initial 为初始值，依次用 f 将窗口中有数据且未过期的桶的值折叠为一个结果
+// 桶按从旧到新的顺序传给 f，空桶(参见 Count)被跳过，窗口为空时返回 initial。
+// 只持有读锁，不旋转窗口，按当前时间跳过已过期的桶；f 在持有读锁时调用，不能在 f 中修改该窗口。
+func (w *TimeWindow) Reduce(f func(acc, value float64) float64, initial float64) float64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	acc := initial
+	for age := w.size - 1 - w.expired(w.now()); age >= 0; age-- {
+		idx := (w.cursor - age + w.size) % w.size
+		if w.populated(idx) {
+			acc = f(acc, w.buckets[idx])
+		}
+	}
+	return acc
+}
+
+// expired 返回旋转到 now 时将被清空的桶的数量，不修改窗口，调用方需持有锁
+func (w *TimeWindow) expired(now time.Time) int {
+	if w.paused {
+		return 0
+	}
+	passed := now.Sub(w.lastTime) / w.bucketDuration()
+	return int(min(max(passed, 0), time.Duration(w.size)))
+}
+
+// GetLatestValue 返回当前桶的值
+// 当前桶自创建或旋转以来未被写入过时返回 false，用以区分"没有数据"和"数据恰好为0"
 func (w *TimeWindow) GetLatestValue() (float64, bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.buckets[w.cursor], w.written[w.cursor]
+}
+
+// GetOldestValue 返回窗口中最旧的桶(年龄为 size-1)的值
+// 该桶自创建或旋转以来未被写入过时返回 false，与 GetLatestValue 配合可以计算整个窗口的变化量
+func (w *TimeWindow) GetOldestValue() (float64, bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	idx := (w.cursor + 1) % w.size
+	return w.buckets[idx], w.written[idx]
+}
+
+// LastNonZero 从当前桶向前查找第一个非零的桶，返回它的值和年龄
+// 适合数据稀疏的窗口在空闲期间显示最近一次的有效值；整个窗口都为0时返回 0, 0, false
+func (w *TimeWindow) LastNonZero() (value float64, age int, ok bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	for age := 0; age < w.size; age++ {
+		if v := w.buckets[(w.cursor-age+w.size)%w.size]; v != 0 {
+			return v, age, true
+		}
+	}
+	return 0, 0, false
+}
+
+// GetValueAt 返回指定桶的值，ago为0表示当前桶，1表示前一个桶，以此类推
+// ago 超出 [0, size) 范围时返回 false
+func (w *TimeWindow) GetValueAt(ago int) (float64, bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	if ago < 0 || ago >= w.size {
+		return 0, false
+	}
+	return w.buckets[(w.cursor-ago+w.size)%w.size], true
+}
+
+// Resize 调整窗口的桶数量，按年龄保留最近的 min(旧大小, 新大小) 个桶
+// 扩大时较旧的一端补零，缩小时丢弃最旧的桶；调整后游标位于0。newSize <= 0 时返回错误。
+func (w *TimeWindow) Resize(newSize int) error {
+	if newSize <= 0 {
+		return fmt.Errorf("invalid window size %d", newSize)
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+
+	buckets := make([]float64, newSize)
+	written := make([]bool, newSize)
+	var counts []int
+	if w.counts != nil {
+		counts = make([]int, newSize)
+	}
+	var weights []float64
+	if w.weights != nil {
+		weights = make([]float64, newSize)
+	}
+	for age := 0; age < min(w.size, newSize); age++ {
+		idx := (w.cursor - age + w.size) % w.size
+		buckets[(newSize-age)%newSize] = w.buckets[idx]
+		written[(newSize-age)%newSize] = w.written[idx]
+		if counts != nil {
+			counts[(newSize-age)%newSize] = w.counts[idx]
+		}
+		if weights != nil {
+			weights[(newSize-age)%newSize] = w.weights[idx]
+		}
+	}
+
+	w.buckets = buckets
+	w.written = written
+	w.counts = counts
+	w.weights = weights
+	w.size = newSize
+	w.cursor = 0
+	w.recompute()
+	return nil
+}
+
+// SetDuration 修改每个桶的时间跨度，保留已有的桶
+// 先按原来的时间跨度旋转到当前时间，然后当前桶从现在开始按新的时间跨度计时；
+// 已有的桶不做重采样，而是按新的时间跨度重新解释，即年龄为 k 的桶被视为 k 个新跨度之前的数据。
+// d <= 0 时与 NewTimeWindow 相同，在下一次旋转时使用默认值5分钟
+func (w *TimeWindow) SetDuration(d time.Duration) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	now := w.now()
+	w.rotate(now)
+	w.duration = d
+	w.lastTime = now
+	if w.paused {
+		w.pausedAt = now
+	}
+}
+
+// Snapshot 返回当前窗口状态的深拷贝，先将窗口旋转到当前时间
+// 快照不与原窗口共享桶，之后对原窗口的写入不会影响快照，可以安全地交给其他 goroutine 读取
+func (w *TimeWindow) Snapshot() *TimeWindow {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	return w.clone()
+}
+
+// Clone 返回当前窗口的独立副本，只持有读锁，不旋转原窗口
+// 副本拥有自己的锁和桶，之后按自身的时钟独立旋转；OnRotate、OnUpdate 和 SetThreshold 的设置不会被复制
+func (w *TimeWindow) Clone() *TimeWindow {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	return w.buckets[w.cursor], true
+	return w.clone()
+}
+
+// clone 复制窗口的所有状态，调用方需持有锁
+func (w *TimeWindow) clone() *TimeWindow {
+	c := &TimeWindow{
+		buckets:    append([]float64(nil), w.buckets...),
+		written:    append([]bool(nil), w.written...),
+		size:       w.size,
+		duration:   w.duration,
+		lastTime:   w.lastTime,
+		cursor:     w.cursor,
+		lastUpdate: w.lastUpdate,
+		createdAt:  w.createdAt,
+		clock:      w.clock,
+		sum:        w.sum,
+		cumulative: w.cumulative,
+		aggMode:    w.aggMode,
+		paused:     w.paused,
+		pausedAt:   w.pausedAt,
+	}
+	if w.prefix != nil {
+		c.prefix = append([]float64(nil), w.prefix...)
+	}
+	if w.counts != nil {
+		c.counts = append([]int(nil), w.counts...)
+	}
+	if w.weights != nil {
+		c.weights = append([]float64(nil), w.weights...)
+	}
+	return c
 }
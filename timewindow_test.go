@@ -1,6 +1,10 @@
 package hstat
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -105,17 +109,68 @@ func BenchmarkTimeWindow_Inc(b *testing.B) {
 	}
 }
 
+func BenchmarkTimeWindow_IncLoop(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	deltas := make([]float64, 64)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, d := range deltas {
+				w.Inc(d)
+			}
+		}
+	})
+}
+
+func BenchmarkTimeWindow_IncBatch(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	deltas := make([]float64, 64)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w.IncBatch(deltas)
+		}
+	})
+}
+
 func BenchmarkTimeWindow_GetData(b *testing.B) {
 	w := NewTimeWindow(60, time.Second)
 	for i := 0; i < 100; i++ {
 		w.Append(float64(i))
 	}
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		w.GetData()
 	}
 }
 
+func BenchmarkTimeWindow_GetDataInto(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	for i := 0; i < 100; i++ {
+		w.Append(float64(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	var buf []TimeWindowData
+	for i := 0; i < b.N; i++ {
+		buf = w.GetDataInto(buf)
+	}
+}
+
+func BenchmarkTimeWindow_GetValuesInto(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	for i := 0; i < 100; i++ {
+		w.Append(float64(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	var buf []float64
+	for i := 0; i < b.N; i++ {
+		buf = w.GetValuesInto(buf)
+	}
+}
+
 func BenchmarkTimeWindow_Sum(b *testing.B) {
 	w := NewTimeWindow(60, time.Second)
 	for i := 0; i < 100; i++ {
@@ -149,3 +204,1123 @@ func BenchmarkTimeWindow_PrintHistogram(b *testing.B) {
 		w.PrintHistogram(opt)
 	}
 }
+
+// fakeClock is a manually advanced clock for deterministic tests
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Add(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestTimeWindow_WithClock(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+
+	if sum := w.Sum(); sum != 3.0 {
+		t.Errorf("Expected sum 3.0, got %f", sum)
+	}
+
+	clock.Add(3 * time.Second)
+	w.Inc(4.0)
+	if sum := w.Sum(); sum != 4.0 {
+		t.Errorf("Expected sum 4.0 after expiry, got %f", sum)
+	}
+}
+
+func TestTimeWindow_IncAtMixedWithClock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	// Replayed data drives the window two buckets ahead of the clock
+	w.IncAt(start.Add(2*time.Second), 5.0)
+	if data := w.GetData(); data[0].Values[0] != 5.0 || data[2].Values[0] != 0 {
+		t.Errorf("Expected replayed value in the current bucket, got %v", data)
+	}
+
+	// The clock is behind the window, so live data lands in the current bucket
+	w.Inc(1.0)
+	if val, _ := w.GetLatestValue(); val != 6.0 {
+		t.Errorf("Expected current value 6.0, got %f", val)
+	}
+
+	// Once the clock catches up and passes, it drives rotation again
+	clock.Add(3 * time.Second)
+	w.Inc(2.0)
+	if val, _ := w.GetLatestValue(); val != 2.0 {
+		t.Errorf("Expected current value 2.0, got %f", val)
+	}
+	if sum := w.Sum(); sum != 8.0 {
+		t.Errorf("Expected sum 8.0, got %f", sum)
+	}
+
+	// An explicit timestamp older than the current bucket lands in its own bucket
+	// and never rewinds the window
+	if err := w.AppendAt(start, 9.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, _ := w.GetLatestValue(); val != 2.0 {
+		t.Errorf("Expected current value 2.0, got %f", val)
+	}
+	if val, _ := w.GetValueAt(3); val != 9.0 {
+		t.Errorf("Expected value 9.0 at age 3, got %f", val)
+	}
+	if err := w.AppendAt(start.Add(-2*time.Second), 9.0); err == nil {
+		t.Error("Expected error for a timestamp older than the window")
+	}
+}
+
+func TestTimeWindow_AppendAt(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Replay out of order: the newest sample first moves the window forward
+	for _, sample := range []struct {
+		offset time.Duration
+		value  float64
+	}{
+		{3 * time.Second, 4.0},
+		{1500 * time.Millisecond, 2.0},
+		{0, 1.0},
+		{2 * time.Second, 3.0},
+	} {
+		if err := w.AppendAt(start.Add(sample.offset), sample.value); err != nil {
+			t.Fatalf("Unexpected error at %s: %v", sample.offset, err)
+		}
+	}
+
+	// Ages 0..3 cover [3s,4s), [2s,3s), [1s,2s), [0s,1s)
+	for age, want := range []float64{4, 3, 2, 1} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+	if sum := w.Sum(); sum != 10.0 {
+		t.Errorf("Expected sum 10.0, got %f", sum)
+	}
+
+	if err := w.AppendAt(start.Add(-time.Nanosecond), 5.0); err == nil {
+		t.Error("Expected error for a timestamp older than the window")
+	}
+	if sum := w.Sum(); sum != 10.0 {
+		t.Errorf("Expected window unchanged after rejection, got sum %f", sum)
+	}
+}
+
+func TestTimeWindow_Advance(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	w.Inc(1.0)
+	w.Advance(clock.Now().Add(5 * time.Second))
+	if sum := w.Sum(); sum != 0 {
+		t.Errorf("Expected sum 0 after advancing past the window, got %f", sum)
+	}
+}
+
+func TestTimeWindow_CurrentBucketElapsed(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	if elapsed := w.CurrentBucketElapsed(); elapsed != 0 {
+		t.Errorf("Expected elapsed 0, got %s", elapsed)
+	}
+
+	clock.Add(250 * time.Millisecond)
+	if elapsed := w.CurrentBucketElapsed(); elapsed != 250*time.Millisecond {
+		t.Errorf("Expected elapsed 250ms, got %s", elapsed)
+	}
+	if fraction := w.CurrentBucketFraction(); fraction != 0.25 {
+		t.Errorf("Expected fraction 0.25, got %f", fraction)
+	}
+
+	// Moving into the next bucket restarts the elapsed time
+	clock.Add(time.Second)
+	if elapsed := w.CurrentBucketElapsed(); elapsed >= time.Second {
+		t.Errorf("Expected elapsed below one bucket, got %s", elapsed)
+	}
+}
+
+func TestTimeWindow_CachedSum(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(10, time.Second, WithClock(clock.Now))
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		delta := rng.Float64() * 100
+		switch rng.Intn(5) {
+		case 0:
+			w.Inc(delta)
+		case 1:
+			w.Dec(delta)
+		case 2:
+			w.Append(delta)
+		case 3:
+			w.Reset(delta)
+		case 4:
+			clock.Add(time.Duration(rng.Intn(3000)) * time.Millisecond)
+			w.Advance(clock.Now())
+		}
+
+		var want float64
+		for _, v := range w.buckets {
+			want += v
+		}
+		if got := w.Sum(); math.Abs(got-want) > 1e-6 {
+			t.Fatalf("Step %d: cached sum %f differs from recomputed %f", i, got, want)
+		}
+	}
+}
+
+func TestTimeWindow_GetValueAt(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 2, 3)
+
+	for ago, want := range []float64{3, 2, 1} {
+		if val, ok := w.GetValueAt(ago); !ok || val != want {
+			t.Errorf("GetValueAt(%d): expected (%f, true), got (%f, %v)", ago, want, val, ok)
+		}
+	}
+
+	if _, ok := w.GetValueAt(3); ok {
+		t.Error("Expected false for ago == size")
+	}
+	if _, ok := w.GetValueAt(-1); ok {
+		t.Error("Expected false for negative ago")
+	}
+}
+
+func TestTimeWindow_GetValueAtExpired(t *testing.T) {
+	clock := newFakeClock()
+	w := newFilledWindow(clock, 1, 2, 3)
+
+	clock.Add(time.Second)
+	if val, _ := w.GetValueAt(0); val != 0 {
+		t.Errorf("Expected expired current bucket to read 0, got %f", val)
+	}
+	if val, _ := w.GetValueAt(1); val != 3 {
+		t.Errorf("Expected value 3 one bucket ago, got %f", val)
+	}
+}
+
+func TestTimeWindow_Snapshot(t *testing.T) {
+	w := NewTimeWindow(5, time.Second)
+	w.Inc(3.0)
+
+	snap := w.Snapshot()
+	w.Inc(4.0)
+	w.Reset(100.0)
+
+	if sum := snap.Sum(); sum != 3.0 {
+		t.Errorf("Expected snapshot sum 3.0, got %f", sum)
+	}
+	if val, _ := snap.GetLatestValue(); val != 3.0 {
+		t.Errorf("Expected snapshot value 3.0, got %f", val)
+	}
+	if &snap.buckets[0] == &w.buckets[0] {
+		t.Error("Snapshot shares the buckets backing array")
+	}
+}
+
+func TestTimeWindow_ResizeGrow(t *testing.T) {
+	clock := newFakeClock()
+	w := newFilledWindow(clock, 1, 2, 3, 4)
+
+	if err := w.Resize(6); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sum := w.Sum(); sum != 10 {
+		t.Errorf("Expected sum 10, got %f", sum)
+	}
+	for age, want := range []float64{4, 3, 2, 1, 0, 0} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+
+	// The resized window keeps rotating normally
+	clock.Add(time.Second)
+	w.Inc(5)
+	if sum := w.Sum(); sum != 15 {
+		t.Errorf("Expected sum 15, got %f", sum)
+	}
+}
+
+func TestTimeWindow_ResizeShrink(t *testing.T) {
+	w := newFilledWindow(newFakeClock(), 1, 2, 3, 4)
+
+	if err := w.Resize(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sum := w.Sum(); sum != 7 {
+		t.Errorf("Expected sum 7 for the two most recent buckets, got %f", sum)
+	}
+	if size := w.Header().Size; size != 2 {
+		t.Errorf("Expected size 2, got %d", size)
+	}
+
+	if err := w.Resize(0); err == nil {
+		t.Error("Expected error for zero size")
+	}
+}
+
+func TestTimeWindow_CountDecrementedToZero(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	w.Inc(4)
+	clock.Add(time.Second)
+	w.Inc(2)
+	w.Dec(2)
+
+	// The bucket driven back to 0 was written, so it still counts
+	if count := w.Count(); count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+	if avg := w.Avg(); avg != 2 {
+		t.Errorf("Expected average 2, got %f", avg)
+	}
+}
+
+func TestTimeWindow_CountNegative(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	w.Append(6)
+	clock.Add(time.Second)
+	w.Append(-3)
+	clock.Add(time.Second)
+	w.Append(0)
+
+	if count := w.Count(); count != 3 {
+		t.Errorf("Expected count 3, got %d", count)
+	}
+	if avg := w.Avg(); avg != 1 {
+		t.Errorf("Expected average 1, got %f", avg)
+	}
+
+	// Rotating a written bucket out clears its flag
+	clock.Add(4 * time.Second)
+	w.Advance(clock.Now())
+	if count := w.Count(); count != 1 {
+		t.Errorf("Expected count 1 after rotation, got %d", count)
+	}
+}
+
+func TestTimeWindow_GetLatestValueWritten(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	if _, ok := w.GetLatestValue(); ok {
+		t.Error("Expected false for a fresh window")
+	}
+
+	w.Inc(2)
+	w.Dec(2)
+	if val, ok := w.GetLatestValue(); !ok || val != 0 {
+		t.Errorf("Expected (0, true) after writes, got (%f, %v)", val, ok)
+	}
+
+	clock.Add(time.Second)
+	if val, ok := w.GetLatestValue(); ok || val != 0 {
+		t.Errorf("Expected (0, false) after the current bucket rotated out, got (%f, %v)", val, ok)
+	}
+}
+
+func TestNewTimeWindowAt(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	w := NewTimeWindowAt(3, time.Second, start, WithClock(func() time.Time { return now }))
+
+	w.Inc(1.0)
+
+	// One nanosecond before the boundary the value is still in the current bucket
+	now = start.Add(time.Second - time.Nanosecond)
+	if val, _ := w.GetValueAt(0); val != 1.0 {
+		t.Errorf("Expected 1.0 in the current bucket, got %f", val)
+	}
+
+	// Exactly at the boundary the window rotates by one bucket
+	now = start.Add(time.Second)
+	if val, _ := w.GetValueAt(1); val != 1.0 {
+		t.Errorf("Expected 1.0 at age 1, got %f", val)
+	}
+	if n := w.ElapsedBuckets(); n != 2 {
+		t.Errorf("Expected 2 elapsed buckets, got %d", n)
+	}
+
+	// Exactly one window span after the write the value expires
+	now = start.Add(3 * time.Second)
+	if val, _ := w.GetValueAt(2); val != 0 {
+		t.Errorf("Expected the value to expire, got %f", val)
+	}
+}
+
+func TestTimeWindow_ElapsedBuckets(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	if n := w.ElapsedBuckets(); n != 1 {
+		t.Errorf("Expected 1 bucket right after creation, got %d", n)
+	}
+
+	clock.Add(2500 * time.Millisecond)
+	if n := w.ElapsedBuckets(); n != 3 {
+		t.Errorf("Expected 3 buckets after 2.5s, got %d", n)
+	}
+
+	// Capped at the window size once the window has filled
+	clock.Add(time.Minute)
+	if n := w.ElapsedBuckets(); n != 5 {
+		t.Errorf("Expected 5 buckets after filling, got %d", n)
+	}
+}
+
+func TestTimeWindow_LastNonZero(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	if _, _, ok := w.LastNonZero(); ok {
+		t.Error("Expected false for an empty window")
+	}
+
+	w.Inc(7)
+	clock.Add(2 * time.Second)
+	if val, age, ok := w.LastNonZero(); !ok || val != 7 || age != 2 {
+		t.Errorf("Expected (7, 2, true), got (%f, %d, %v)", val, age, ok)
+	}
+
+	// Buckets written back to zero are skipped
+	w.Inc(3)
+	w.Dec(3)
+	if val, age, _ := w.LastNonZero(); val != 7 || age != 2 {
+		t.Errorf("Expected (7, 2), got (%f, %d)", val, age)
+	}
+
+	clock.Add(2 * time.Second)
+	if _, _, ok := w.LastNonZero(); ok {
+		t.Error("Expected false after the value expired")
+	}
+}
+
+func TestTimeWindow_ForEach(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	clock.Add(time.Second)
+	w.Inc(3.0)
+
+	var ages []int
+	var values []float64
+	w.ForEach(OldestFirst, func(age int, ts time.Time, value float64) bool {
+		if want := clock.Now().Add(-time.Duration(age) * time.Second); !ts.Equal(want) {
+			t.Errorf("Age %d: expected time %v, got %v", age, want, ts)
+		}
+		ages = append(ages, age)
+		values = append(values, value)
+		return true
+	})
+	for i, want := range []float64{1, 2, 3} {
+		if ages[i] != 2-i || values[i] != want {
+			t.Errorf("Step %d: expected age %d value %f, got age %d value %f", i, 2-i, want, ages[i], values[i])
+		}
+	}
+
+	// Newest first, stopping after the first bucket
+	calls := 0
+	w.ForEach(NewestFirst, func(age int, _ time.Time, value float64) bool {
+		calls++
+		if age != 0 || value != 3.0 {
+			t.Errorf("Expected age 0 value 3.0, got age %d value %f", age, value)
+		}
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestTimeWindow_ForEachNoAlloc(t *testing.T) {
+	w := NewTimeWindow(60, time.Second)
+	w.Inc(1.0)
+
+	var sum float64
+	fn := func(_ int, _ time.Time, value float64) bool {
+		sum += value
+		return true
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		w.ForEach(OldestFirst, fn)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+}
+
+func TestTimeWindow_Clone(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(5.0)
+
+	c := w.Clone()
+	if c.Sum() != 5.0 {
+		t.Errorf("Expected clone sum 5.0, got %f", c.Sum())
+	}
+
+	c.Inc(10.0)
+	c.Reset(1.0)
+	if w.Sum() != 5.0 {
+		t.Errorf("Expected original sum 5.0, got %f", w.Sum())
+	}
+
+	// Both rotate independently with the shared clock
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	if c.Sum() != 1.0 || w.Sum() != 7.0 {
+		t.Errorf("Expected sums 1.0 and 7.0, got %f and %f", c.Sum(), w.Sum())
+	}
+}
+
+func TestTimeWindow_Clear(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now), WithPrefixSums())
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	clock.Add(500 * time.Millisecond)
+
+	w.Clear()
+	if w.Sum() != 0 || w.Count() != 0 {
+		t.Errorf("Expected sum 0 and count 0, got %f and %d", w.Sum(), w.Count())
+	}
+	if h := w.Header(); h.Cursor != 0 || !h.LastTime.Equal(clock.Now()) {
+		t.Errorf("Expected cursor 0 and last time reset, got %+v", h)
+	}
+
+	// The window keeps working after Clear
+	w.Inc(3.0)
+	clock.Add(time.Second)
+	w.Inc(4.0)
+	if w.Sum() != 7.0 || w.SumRange(0, 1) != 7.0 {
+		t.Errorf("Expected sum 7.0, got %f (range %f)", w.Sum(), w.SumRange(0, 1))
+	}
+}
+
+func TestTimeWindow_ResetAll(t *testing.T) {
+	w := NewTimeWindow(4, time.Second)
+	w.Inc(9.0)
+
+	w.ResetAll(2.0)
+	if w.Sum() != 8.0 || w.Count() != 4 {
+		t.Errorf("Expected sum 8.0 and count 4, got %f and %d", w.Sum(), w.Count())
+	}
+
+	w.ResetAll(0)
+	if w.Sum() != 0 || w.Count() != 4 {
+		t.Errorf("Expected sum 0 and count 4, got %f and %d", w.Sum(), w.Count())
+	}
+}
+
+func TestTimeWindow_GetRange(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(60, time.Second, WithClock(clock.Now))
+	for i := 1; i <= 60; i++ {
+		clock.Add(time.Second)
+		w.Inc(float64(i))
+	}
+	now := clock.Now()
+
+	// Both ends are inclusive: ages 10..5 hold 50..55
+	data := w.GetRange(now.Add(-10*time.Second), now.Add(-5*time.Second))
+	if len(data) != 6 {
+		t.Fatalf("Expected 6 buckets, got %d", len(data))
+	}
+	for i, d := range data {
+		if want := float64(50 + i); d.Values[0] != want {
+			t.Errorf("Bucket %d: expected %f, got %f", i, want, d.Values[0])
+		}
+		if want := now.Add(time.Duration(i-10) * time.Second); !d.Time.Equal(want) {
+			t.Errorf("Bucket %d: expected time %v, got %v", i, want, d.Time)
+		}
+	}
+
+	// A range reaching past both ends is clamped to the window
+	if data := w.GetRange(now.Add(-time.Hour), now.Add(time.Hour)); len(data) != 60 {
+		t.Errorf("Expected 60 buckets, got %d", len(data))
+	}
+
+	if data := w.GetRange(now.Add(time.Second), now.Add(time.Hour)); data == nil || len(data) != 0 {
+		t.Errorf("Expected an empty slice, got %v", data)
+	}
+}
+
+func TestTimeWindow_AddAndGet(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	if val := w.AddAndGet(2.0); val != 2.0 {
+		t.Errorf("Expected 2.0, got %f", val)
+	}
+	if val := w.AddAndGet(3.0); val != 5.0 {
+		t.Errorf("Expected 5.0, got %f", val)
+	}
+	if val := w.DecAndGet(1.0); val != 4.0 {
+		t.Errorf("Expected 4.0, got %f", val)
+	}
+
+	// The returned value is the new current bucket after rotation
+	clock.Add(time.Second)
+	if val := w.AddAndGet(1.0); val != 1.0 {
+		t.Errorf("Expected 1.0 after rotation, got %f", val)
+	}
+	if sum := w.Sum(); sum != 5.0 {
+		t.Errorf("Expected sum 5.0, got %f", sum)
+	}
+}
+
+func TestTimeWindow_GetDataWritten(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	// Ages 3..0: untouched, written zero, written nonzero, untouched
+	clock.Add(time.Second)
+	w.Append(0)
+	clock.Add(time.Second)
+	w.Inc(5.0)
+	clock.Add(time.Second)
+
+	data := w.GetData()
+	for age, want := range []bool{false, true, true, false} {
+		if data[age].Written != want {
+			t.Errorf("Age %d: expected written %v, got %v", age, want, data[age].Written)
+		}
+	}
+	if data[2].Values[0] != 0 || data[3].Values[0] != 0 {
+		t.Errorf("Expected zero values for ages 2 and 3, got %f and %f", data[2].Values[0], data[3].Values[0])
+	}
+
+	// Rotation clears the flag together with the value: the written zero expires
+	// while the nonzero bucket moves to age 3
+	clock.Add(2 * time.Second)
+	data = w.GetData()
+	for age, want := range []bool{false, false, false, true} {
+		if data[age].Written != want {
+			t.Errorf("Age %d: expected written %v after rotation, got %v", age, want, data[age].Written)
+		}
+	}
+}
+
+func TestTimeWindow_Total(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(2, time.Second, WithClock(clock.Now))
+
+	w.Inc(5.0)
+	w.Dec(2.0)
+	clock.Add(time.Second)
+	w.Inc(4.0)
+
+	// Append overwrites 4 with 10, contributing the difference of 6
+	w.Append(10.0)
+	if total := w.Total(); total != 13.0 {
+		t.Errorf("Expected total 13.0, got %f", total)
+	}
+
+	// Several rotations expire everything from the window but not from the total
+	for i := 0; i < 5; i++ {
+		clock.Add(time.Second)
+		w.Inc(1.0)
+	}
+	if sum := w.Sum(); sum != 2.0 {
+		t.Errorf("Expected sum 2.0, got %f", sum)
+	}
+	if total := w.Total(); total != 18.0 {
+		t.Errorf("Expected total 18.0, got %f", total)
+	}
+
+	w.Clear()
+	if total := w.Total(); total != 18.0 {
+		t.Errorf("Expected total 18.0 after Clear, got %f", total)
+	}
+}
+
+func TestTimeWindow_TryInc(t *testing.T) {
+	w := NewTimeWindow(3, time.Second)
+
+	if !w.TryInc(1.0) {
+		t.Error("Expected TryInc to succeed on an idle window")
+	}
+
+	// Simulate a slow reader holding the lock
+	w.mu.RLock()
+	if w.TryInc(2.0) {
+		t.Error("Expected TryInc to fail while the lock is held")
+	}
+	w.mu.RUnlock()
+
+	if sum := w.Sum(); sum != 1.0 {
+		t.Errorf("Expected the dropped sample to be lost, got sum %f", sum)
+	}
+}
+
+func BenchmarkTimeWindow_TryIncContended(b *testing.B) {
+	w := NewTimeWindow(60, time.Second)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				w.PrintHistogram(nil)
+			}
+		}
+	}()
+
+	var dropped int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !w.TryInc(1.0) {
+			dropped++
+		}
+	}
+	b.StopTimer()
+	close(done)
+	b.ReportMetric(float64(dropped)/float64(b.N), "dropped/op")
+}
+
+func TestTimeWindow_InvalidParameters(t *testing.T) {
+	if _, err := NewTimeWindowChecked(0, time.Second); err == nil {
+		t.Error("Expected error for zero size")
+	}
+	if _, err := NewTimeWindowChecked(-1, time.Second); err == nil {
+		t.Error("Expected error for negative size")
+	}
+	if _, err := NewTimeWindowChecked(3, 0); err == nil {
+		t.Error("Expected error for zero duration")
+	}
+	if _, err := NewTimeWindowChecked(3, -time.Second); err == nil {
+		t.Error("Expected error for negative duration")
+	}
+
+	w, err := NewTimeWindowChecked(3, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w.Inc(1.0)
+
+	// NewTimeWindow clamps instead of producing a window that panics on write
+	for _, size := range []int{0, -5} {
+		w := NewTimeWindow(size, -time.Second)
+		w.Inc(2.0)
+		if h := w.Header(); h.Size != 1 || w.Sum() != 2.0 {
+			t.Errorf("Size %d: expected a single bucket holding 2.0, got size %d sum %f", size, h.Size, w.Sum())
+		}
+	}
+}
+
+func TestTimeWindow_GetDataInto(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+
+	buf := w.GetDataInto(nil)
+	want := w.GetData()
+	for i := range want {
+		if !buf[i].Time.Equal(want[i].Time) || buf[i].Values[0] != want[i].Values[0] || buf[i].Written != want[i].Written {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, want[i], buf[i])
+		}
+	}
+
+	// Reusing the buffer does not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = w.GetDataInto(buf)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %f", allocs)
+	}
+
+	values := w.GetValuesInto(make([]float64, 0, 3))
+	if len(values) != 3 || values[0] != 2.0 || values[1] != 1.0 || values[2] != 0 {
+		t.Errorf("Expected [2 1 0], got %v", values)
+	}
+}
+
+func TestTimeWindow_IncAtLateArrivals(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	events := []struct {
+		offset time.Duration
+		delta  float64
+	}{
+		{0, 1.0},
+		{2 * time.Second, 4.0},
+		{500 * time.Millisecond, 2.0}, // late, lands at age 2
+		{1200 * time.Millisecond, 3.0},
+		{2500 * time.Millisecond, -1.0},
+	}
+	for _, e := range events {
+		if err := w.IncAt(start.Add(e.offset), e.delta); err != nil {
+			t.Fatalf("Unexpected error at %s: %v", e.offset, err)
+		}
+	}
+
+	for age, want := range []float64{3, 3, 3} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+	if !w.LastUpdateTime().Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Expected last update at the newest event, got %v", w.LastUpdateTime())
+	}
+
+	// Events older than the window are dropped with ErrTooOld
+	err := w.IncAt(start.Add(-time.Second), 10.0)
+	if !errors.Is(err, ErrTooOld) {
+		t.Errorf("Expected ErrTooOld, got %v", err)
+	}
+	if sum := w.Sum(); sum != 9.0 {
+		t.Errorf("Expected sum 9.0, got %f", sum)
+	}
+
+	// Times far enough back to saturate time.Time.Sub must not wrap into a live bucket
+	for _, old := range []time.Time{{}, start.AddDate(-400, 0, 0)} {
+		if err := w.IncAt(old, 7.0); !errors.Is(err, ErrTooOld) {
+			t.Errorf("IncAt(%v): expected ErrTooOld, got %v", old, err)
+		}
+		if err := w.AppendAt(old, 7.0); !errors.Is(err, ErrTooOld) {
+			t.Errorf("AppendAt(%v): expected ErrTooOld, got %v", old, err)
+		}
+	}
+	if sum := w.Sum(); sum != 9.0 {
+		t.Errorf("Expected sum 9.0, got %f", sum)
+	}
+}
+
+func TestTimeWindow_GetOldestValue(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	// Partial fill: the oldest bucket has not been reached yet
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	if val, ok := w.GetOldestValue(); ok || val != 0 {
+		t.Errorf("Expected unwritten oldest bucket, got %f (%v)", val, ok)
+	}
+
+	// After wrapping around, the oldest bucket holds the value from two rotations ago
+	clock.Add(time.Second)
+	w.Inc(3.0)
+	clock.Add(time.Second)
+	w.Inc(4.0)
+	if val, ok := w.GetOldestValue(); !ok || val != 2.0 {
+		t.Errorf("Expected oldest value 2.0, got %f (%v)", val, ok)
+	}
+
+	latest, _ := w.GetLatestValue()
+	oldest, _ := w.GetOldestValue()
+	if delta := latest - oldest; delta != 2.0 {
+		t.Errorf("Expected delta 2.0, got %f", delta)
+	}
+}
+
+func TestTimeWindow_StalenessGaugeWrites(t *testing.T) {
+	// Gauge style writes refresh the last update time just like Inc
+	writes := map[string]func(w *TimeWindow){
+		"Append":      func(w *TimeWindow) { w.Append(3.0) },
+		"AppendBatch": func(w *TimeWindow) { w.AppendBatch([]float64{1.0, 3.0}) },
+		"Reset":       func(w *TimeWindow) { w.Reset(3.0) },
+	}
+	for name, write := range writes {
+		clock := newFakeClock()
+		w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+		write(w)
+
+		if !w.LastUpdateTime().Equal(clock.Now()) {
+			t.Errorf("%s: expected last update %v, got %v", name, clock.Now(), w.LastUpdateTime())
+		}
+		if w.IsStale(time.Second) {
+			t.Errorf("%s: expected a freshly written window not to be stale", name)
+		}
+	}
+}
+
+func TestTimeWindow_Staleness(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	// Never updated
+	if !w.IsStale(time.Hour) {
+		t.Error("Expected a never updated window to be stale")
+	}
+	if d := w.StaleFor(); d != math.MaxInt64 {
+		t.Errorf("Expected max duration, got %s", d)
+	}
+
+	w.Inc(1.0)
+	clock.Add(1500 * time.Millisecond)
+	if d := w.StaleFor(); d != 1500*time.Millisecond {
+		t.Errorf("Expected 1.5s, got %s", d)
+	}
+	if w.IsStale(2 * time.Second) {
+		t.Error("Expected window not to be stale within 2s")
+	}
+	if !w.IsStale(time.Second) {
+		t.Error("Expected window to be stale after 1s")
+	}
+
+	// Replayed data ahead of the clock is not stale
+	if err := w.IncAt(clock.Now().Add(time.Minute), 1.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d := w.StaleFor(); d != 0 {
+		t.Errorf("Expected 0 for an update ahead of the clock, got %s", d)
+	}
+}
+
+func TestTimeWindow_RotateNoDrift(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(5, time.Second, WithClock(clock.Now))
+
+	// Ten increments 300ms apart span 0s..2.7s; buckets must split at
+	// exactly 1s and 2s from the start instead of at the rotating call
+	for i := 0; i < 10; i++ {
+		w.Inc(1.0)
+		clock.Add(300 * time.Millisecond)
+	}
+	clock.Add(-300 * time.Millisecond)
+
+	for age, want := range []float64{3, 3, 4} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+	if d := w.CurrentBucketElapsed(); d != 700*time.Millisecond {
+		t.Errorf("Expected 700ms into the current bucket, got %s", d)
+	}
+
+	// The next boundary is at 3s, not 1s after the last rotating call
+	clock.Add(300 * time.Millisecond)
+	w.Inc(1.0)
+	if val, _ := w.GetValueAt(0); val != 1 {
+		t.Errorf("Expected a fresh bucket at 3s, got %f", val)
+	}
+}
+
+func TestTimeWindow_SetDuration(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	clock.Add(500 * time.Millisecond)
+
+	w.SetDuration(time.Minute)
+	if d := w.Header().Duration; d != time.Minute {
+		t.Errorf("Expected duration 1m, got %s", d)
+	}
+
+	// Existing buckets are kept and the current bucket starts now
+	w.Inc(3.0)
+	if val, _ := w.GetLatestValue(); val != 5.0 {
+		t.Errorf("Expected latest value 5.0, got %f", val)
+	}
+	if sum := w.Sum(); sum != 6.0 {
+		t.Errorf("Expected sum 6.0, got %f", sum)
+	}
+
+	// Rotation follows the new cadence
+	clock.Add(30 * time.Second)
+	w.Inc(1.0)
+	if val, _ := w.GetLatestValue(); val != 6.0 {
+		t.Errorf("Expected no rotation within 1m, got latest %f", val)
+	}
+	clock.Add(30 * time.Second)
+	w.Inc(4.0)
+	for age, want := range []float64{4, 6, 1} {
+		if val, _ := w.GetValueAt(age); val != want {
+			t.Errorf("Age %d: expected %f, got %f", age, want, val)
+		}
+	}
+}
+
+func TestTimeWindow_String(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	want := "TimeWindow{size=4 duration=1s sum=0 count=0 avg=0 last_update=never}"
+	if s := fmt.Sprintf("%v", w); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+
+	w.Inc(1.0)
+	clock.Add(time.Second)
+	w.Inc(2.0)
+	want = "TimeWindow{size=4 duration=1s sum=3 count=2 avg=1.5 last_update=2024-01-01T00:00:01Z}"
+	if s := w.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+
+	// A non-positive duration prints the default that is actually used
+	want = "TimeWindow{size=2 duration=5m0s sum=0 count=0 avg=0 last_update=never}"
+	if s := NewTimeWindow(2, 0).String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+}
+
+func TestTimeWindow_Batch(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(4, time.Second, WithClock(clock.Now))
+
+	w.Inc(1.0)
+	clock.Add(time.Second)
+
+	// The batch rotates once and lands in a single bucket
+	w.IncBatch([]float64{1, 2, 3})
+	if val, _ := w.GetLatestValue(); val != 6.0 {
+		t.Errorf("Expected latest value 6.0, got %f", val)
+	}
+	if sum := w.Sum(); sum != 7.0 {
+		t.Errorf("Expected sum 7.0, got %f", sum)
+	}
+	if got := w.LastUpdateTime(); !got.Equal(clock.Now()) {
+		t.Errorf("Expected last update %v, got %v", clock.Now(), got)
+	}
+
+	w.AppendBatch([]float64{4, 5})
+	if val, _ := w.GetLatestValue(); val != 5.0 {
+		t.Errorf("Expected latest value 5.0, got %f", val)
+	}
+
+	// Empty batches are no-ops
+	w.IncBatch(nil)
+	w.AppendBatch(nil)
+	if sum := w.Sum(); sum != 6.0 {
+		t.Errorf("Expected sum 6.0, got %f", sum)
+	}
+}
+
+func TestTimeWindow_Geometry(t *testing.T) {
+	w := NewTimeWindow(60, time.Second)
+	if n := w.Size(); n != 60 {
+		t.Errorf("Expected size 60, got %d", n)
+	}
+	if d := w.Duration(); d != time.Second {
+		t.Errorf("Expected duration 1s, got %s", d)
+	}
+	if d := w.WindowSpan(); d != time.Minute {
+		t.Errorf("Expected span 1m, got %s", d)
+	}
+
+	// A non-positive duration reports the default
+	w = NewTimeWindow(2, 0)
+	if d := w.Duration(); d != 5*time.Minute {
+		t.Errorf("Expected default duration 5m, got %s", d)
+	}
+	if d := w.WindowSpan(); d != 10*time.Minute {
+		t.Errorf("Expected span 10m, got %s", d)
+	}
+}
+
+func TestTimeWindow_Reduce(t *testing.T) {
+	clock := newFakeClock()
+	w := newFilledWindow(clock, 3, 0, 5, 1)
+
+	sum := w.Reduce(func(acc, v float64) float64 { return acc + v }, 0)
+	if sum != 9 {
+		t.Errorf("Expected sum 9, got %f", sum)
+	}
+	maxValue := w.Reduce(math.Max, math.Inf(-1))
+	if maxValue != 5 {
+		t.Errorf("Expected max 5, got %f", maxValue)
+	}
+
+	// Values are passed oldest first
+	var order []float64
+	w.Reduce(func(acc, v float64) float64 {
+		order = append(order, v)
+		return acc
+	}, 0)
+	if len(order) != 3 || order[0] != 3 || order[1] != 5 || order[2] != 1 {
+		t.Errorf("Expected order [3 5 1], got %v", order)
+	}
+
+	// Expired buckets are skipped without rotating the window
+	clock.Add(2 * time.Second)
+	sum = w.Reduce(func(acc, v float64) float64 { return acc + v }, 0)
+	if sum != 6 {
+		t.Errorf("Expected sum 6 after expiry, got %f", sum)
+	}
+	if h := w.Header(); !h.LastTime.Equal(clock.Now().Add(-2 * time.Second)) {
+		t.Errorf("Expected Reduce not to rotate, got last time %v", h.LastTime)
+	}
+
+	if got := NewTimeWindow(3, time.Second).Reduce(math.Max, -1); got != -1 {
+		t.Errorf("Expected the initial value for an empty window, got %f", got)
+	}
+}
+
+func TestTimeWindow_GetDataChronological(t *testing.T) {
+	clock := newFakeClock()
+	w := newFilledWindow(clock, 1, 2, 3)
+
+	data := w.GetDataChronological()
+	if len(data) != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", len(data))
+	}
+	for i, want := range []float64{1, 2, 3} {
+		if data[i].Values[0] != want {
+			t.Errorf("Index %d: expected %f, got %f", i, want, data[i].Values[0])
+		}
+		if i > 0 && !data[i].Time.After(data[i-1].Time) {
+			t.Errorf("Index %d: expected times in ascending order, got %v after %v", i, data[i].Time, data[i-1].Time)
+		}
+	}
+
+	// The last element is the current bucket at the most recent time
+	last := data[len(data)-1]
+	if cur, _ := w.GetLatestValue(); last.Values[0] != cur {
+		t.Errorf("Expected the last element to be the current bucket %f, got %f", cur, last.Values[0])
+	}
+	if !last.Time.Equal(clock.Now()) {
+		t.Errorf("Expected the last element at %v, got %v", clock.Now(), last.Time)
+	}
+}
+
+func TestTimeWindow_SumKahan(t *testing.T) {
+	// Many small values: naive accumulation drifts away from 1000
+	w := NewTimeWindow(10000, time.Second)
+	w.ResetAll(0.1)
+	if sum := w.Sum(); sum == 1000 {
+		t.Fatalf("Expected the naive sum to drift, got exactly %f", sum)
+	}
+	if sum := w.SumKahan(); sum != 1000 {
+		t.Errorf("Expected compensated sum 1000, got %.17g", sum)
+	}
+
+	// Values of very different magnitude cancel out in the naive sum
+	w = newFilledWindow(newFakeClock(), 1, 1e100, 1, -1e100)
+	if sum := w.Sum(); sum != 0 {
+		t.Errorf("Expected the naive sum to lose the small values, got %f", sum)
+	}
+	if sum := w.SumKahan(); sum != 2 {
+		t.Errorf("Expected compensated sum 2, got %f", sum)
+	}
+}
@@ -0,0 +1,29 @@
+package hstat
+
+import "context"
+
+// WaitForSum 阻塞直到窗口的 Sum 不小于 target，或 ctx 被取消
+// 达到目标时返回 nil，ctx 被取消时返回 ctx.Err()。等待不轮询，而是在窗口的和发生变化时被唤醒；
+// 因旋转导致的变化只有在有其他调用(写入、读取或 StartJanitor)旋转窗口时才会被观察到
+func (w *TimeWindow) WaitForSum(ctx context.Context, target float64) error {
+	for {
+		w.mu.Lock()
+		w.rotate(w.now())
+		if w.sum >= target {
+			w.unlock()
+			return nil
+		}
+		if w.sumChanged == nil {
+			w.sumChanged = make(chan struct{})
+			w.sumSeen = w.sum
+		}
+		changed := w.sumChanged
+		w.unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
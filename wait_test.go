@@ -0,0 +1,50 @@
+package hstat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_WaitForSum(t *testing.T) {
+	w := NewTimeWindow(10, time.Minute)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.WaitForSum(context.Background(), 10)
+	}()
+
+	for i := 0; i < 5; i++ {
+		w.Inc(2.0)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected WaitForSum to return once the target is reached")
+	}
+	if sum := w.Sum(); sum < 10 {
+		t.Errorf("Expected sum of at least 10, got %f", sum)
+	}
+
+	// An already reached target returns immediately
+	if err := w.WaitForSum(context.Background(), 5); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTimeWindow_WaitForSumCancelled(t *testing.T) {
+	w := NewTimeWindow(10, time.Minute)
+	w.Inc(1.0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := w.WaitForSum(ctx, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
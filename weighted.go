@@ -0,0 +1,33 @@
+package hstat
+
+// IncWeighted 将 delta*weight 累加到当前桶，并记录当前桶的总权重，用于 WeightedBucketAvg
+// 与 Inc 相互独立：桶的值(以及 Sum 等聚合)包含加权后的值，但 Inc 等普通写入不计入权重，
+// 因此同一个桶内混用两者时加权平均没有意义。权重数组在第一次调用时分配，随桶一起旋转清空。
+// weight 应为正数，<= 0 时忽略本次调用
+func (w *TimeWindow) IncWeighted(delta, weight float64) {
+	if !(weight > 0) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.unlock()
+
+	if w.weights == nil {
+		w.weights = make([]float64, w.size)
+	}
+	w.incAt(w.now(), delta*weight)
+	w.weights[w.cursor] += weight
+}
+
+// WeightedBucketAvg 返回当前桶内 IncWeighted 写入的加权平均值，即桶的值除以总权重
+// 当前桶没有通过 IncWeighted 写入过时返回 0, false
+func (w *TimeWindow) WeightedBucketAvg() (float64, bool) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.rotate(w.now())
+	if w.weights == nil || w.weights[w.cursor] == 0 {
+		return 0, false
+	}
+	return w.buckets[w.cursor] / w.weights[w.cursor], true
+}
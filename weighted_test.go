@@ -0,0 +1,61 @@
+package hstat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_IncWeighted(t *testing.T) {
+	clock := newFakeClock()
+	w := NewTimeWindow(3, time.Second, WithClock(clock.Now))
+
+	if _, ok := w.WeightedBucketAvg(); ok {
+		t.Error("Expected false before any weighted writes")
+	}
+
+	// A later sample with weight 3 pulls the average towards it
+	w.IncWeighted(10, 1)
+	w.IncWeighted(20, 3)
+	w.IncWeighted(100, 0) // ignored
+
+	if avg, ok := w.WeightedBucketAvg(); !ok || avg != 17.5 {
+		t.Errorf("Expected (17.5, true), got (%f, %v)", avg, ok)
+	}
+	if sum := w.Sum(); sum != 70 {
+		t.Errorf("Expected sum of weighted values 70, got %f", sum)
+	}
+
+	// Weights rotate out with their bucket
+	clock.Add(time.Second)
+	if _, ok := w.WeightedBucketAvg(); ok {
+		t.Error("Expected false for a fresh bucket")
+	}
+	w.IncWeighted(4, 2)
+	if avg, _ := w.WeightedBucketAvg(); avg != 4 {
+		t.Errorf("Expected average 4, got %f", avg)
+	}
+
+	// Clones carry the weights
+	if avg, _ := w.Clone().WeightedBucketAvg(); avg != 4 {
+		t.Errorf("Expected cloned average 4, got %f", avg)
+	}
+}
+
+func TestTimeWindow_IncWeightedDecode(t *testing.T) {
+	data, err := json.Marshal(NewTimeWindow(3, time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	w := NewTimeWindow(3, time.Second)
+	w.IncWeighted(1, 5)
+
+	// Decoded buckets carry no weights, so the old ones must not survive
+	if err := json.Unmarshal(data, w); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if avg, ok := w.WeightedBucketAvg(); ok {
+		t.Errorf("Expected no weighted average after decoding, got %f", avg)
+	}
+}